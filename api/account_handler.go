@@ -0,0 +1,231 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"golang-order-matching-system/db"
+	"golang-order-matching-system/engine"
+	"golang-order-matching-system/models"
+	"golang-order-matching-system/utils"
+	"github.com/gorilla/mux"
+)
+
+// GetAccount handles GET /account to retrieve the authenticated account
+func GetAccount(w http.ResponseWriter, r *http.Request) {
+	utils.JSONResponse(w, http.StatusOK, accountFrom(r))
+}
+
+// GetAccountOrders handles GET /account/orders to list the orders of the authenticated account
+func GetAccountOrders(w http.ResponseWriter, r *http.Request) {
+	orders, err := db.GetOrdersByAccount(accountFrom(r).ID, r.URL.Query().Get("status"))
+	if err != nil {
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to get orders")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, orders)
+}
+
+// GetAccountBalances handles GET /account/balances to list the balances of the authenticated account
+func GetAccountBalances(w http.ResponseWriter, r *http.Request) {
+	balances, err := db.GetBalances(accountFrom(r).ID)
+	if err != nil {
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to get balances")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, balances)
+}
+
+// AdjustBalance handles POST /admin/accounts/{id}/balances to deposit (positive
+// amount) or withdraw (negative amount) available funds of an account
+func AdjustBalance(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	var req struct {
+		Asset  string         `json:"asset"`
+		Amount models.Decimal `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if req.Asset == "" || len(req.Asset) > 10 {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Asset is required and must be at most 10 characters")
+		return
+	}
+	if req.Amount.IsZero() {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Amount must not be 0")
+		return
+	}
+
+	account, err := db.GetAccount(accountID)
+	if err != nil {
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve account")
+		return
+	}
+	if account == nil {
+		utils.JSONErrorResponse(w, http.StatusNotFound, "Account not found")
+		return
+	}
+
+	journal := &models.Journal{
+		Type:        models.JournalTypeDeposit,
+		ReferenceID: accountID,
+		Entries: []models.LedgerEntry{
+			{AccountID: accountID, Asset: req.Asset, Bucket: models.BucketAvailable, Amount: req.Amount},
+			{AccountID: models.ExternalAccountID, Asset: req.Asset, Bucket: models.BucketAvailable, Amount: req.Amount.Neg()},
+		},
+	}
+	if req.Amount.Sign() < 0 {
+		journal.Type = models.JournalTypeWithdrawal
+	}
+	if err := db.PostJournalTx(journal, nil); err != nil {
+		if err == db.ErrInsufficientFunds {
+			utils.JSONErrorCodeResponse(w, http.StatusBadRequest, engine.RejectInsufficientFunds, "insufficient funds")
+			return
+		}
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to adjust balance")
+		return
+	}
+
+	balances, err := db.GetBalances(accountID)
+	if err != nil {
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to get balances")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, balances)
+}
+
+// GetAccounts handles GET /admin/accounts to list all accounts
+func GetAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := db.GetAccounts()
+	if err != nil {
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to get accounts")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, accounts)
+}
+
+// CreateAccount handles POST /admin/accounts to register a new account
+func CreateAccount(w http.ResponseWriter, r *http.Request) {
+	var account models.Account
+	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if account.Name == "" || len(account.Name) > 100 {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Name is required and must be at most 100 characters")
+		return
+	}
+	switch account.Role {
+	case "":
+		account.Role = models.AccountRoleTrader
+	case models.AccountRoleTrader, models.AccountRoleAdmin:
+	default:
+		utils.JSONErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid role: %s, must be one of trader, admin", account.Role))
+		return
+	}
+	account.Status = models.AccountStatusActive
+	account.CreatedAt = time.Now()
+
+	if err := db.CreateAccount(&account); err != nil {
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to create account")
+		return
+	}
+	utils.JSONResponse(w, http.StatusCreated, account)
+}
+
+// CreateAPIKey handles POST /admin/accounts/{id}/api_keys to issue a new API key.
+// The secret is only returned in this response.
+func CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+	account, err := db.GetAccount(accountID)
+	if err != nil {
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve account")
+		return
+	}
+	if account == nil {
+		utils.JSONErrorResponse(w, http.StatusNotFound, "Account not found")
+		return
+	}
+
+	key := models.APIKey{
+		AccountID: accountID,
+		Key:       randomHex(16),
+		Secret:    randomHex(32),
+		CreatedAt: time.Now(),
+	}
+	if err := db.CreateAPIKey(&key); err != nil {
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+	utils.JSONResponse(w, http.StatusCreated, key)
+}
+
+// RevokeAPIKey handles DELETE /admin/api_keys/{key} to disable an API key
+func RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if err := db.RevokeAPIKey(mux.Vars(r)["key"]); err != nil {
+		if err == sql.ErrNoRows {
+			utils.JSONErrorResponse(w, http.StatusNotFound, "API key not found")
+			return
+		}
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to revoke API key")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BootstrapAdminKey issues an API key for the bootstrap admin account 1 when no
+// admin key exists yet. The key and secret come from ADMIN_API_KEY and
+// ADMIN_API_SECRET, or are generated and logged once if those are unset.
+func BootstrapAdminKey() error {
+	exists, err := db.HasAdminAPIKey()
+	if err != nil || exists {
+		return err
+	}
+	key := models.APIKey{
+		AccountID: 1,
+		Key:       os.Getenv("ADMIN_API_KEY"),
+		Secret:    os.Getenv("ADMIN_API_SECRET"),
+		CreatedAt: time.Now(),
+	}
+	generated := key.Key == "" || key.Secret == ""
+	if generated {
+		key.Key = randomHex(16)
+		key.Secret = randomHex(32)
+	}
+	if err := db.CreateAPIKey(&key); err != nil {
+		return err
+	}
+	if generated {
+		log.Printf("Issued admin API key %s with secret %s; store them now, they are not shown again", key.Key, key.Secret)
+	} else {
+		log.Printf("Issued admin API key %s from ADMIN_API_KEY", key.Key)
+	}
+	return nil
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand never fails on supported platforms
+	}
+	return hex.EncodeToString(b)
+}
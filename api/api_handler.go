@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"database/sql"
 	"golang-order-matching-system/db"
 	"golang-order-matching-system/engine"
 	"golang-order-matching-system/models"
@@ -16,14 +15,50 @@ import (
 
 var orderBook *engine.OrderBook
 
-// SetupRoutes sets up the API routes
-func SetupRoutes(r *mux.Router) {
+// SetupRoutes sets up the API routes backed by the given order book.
+// Every mutating route must be signed, see VerifySignature.
+func SetupRoutes(r *mux.Router, ob *engine.OrderBook) {
+	orderBook = ob
+	r.Use(VerifySignature)
 	r.HandleFunc("/orders", CreateOrder).Methods("POST")
 	r.HandleFunc("/orders/{id}", CancelOrder).Methods("DELETE")
+	r.HandleFunc("/orders/{id}", AmendOrder).Methods("PATCH")
 	r.HandleFunc("/orderbook", GetOrderBook).Methods("GET")
+	r.HandleFunc("/orderbook/depth", GetDepth).Methods("GET")
+	r.HandleFunc("/orderbook/l3", GetBookSnapshot).Methods("GET")
 	r.HandleFunc("/trades", GetTrades).Methods("GET")
+	r.HandleFunc("/ws/market", StreamMarketData).Methods("GET")
+	r.HandleFunc("/ws/account", StreamExecutions).Methods("GET")
+	r.HandleFunc("/stream/trades", StreamTrades).Methods("GET")
 	r.HandleFunc("/orders/{id}/status", UpdateOrderStatus).Methods("PUT")
 	r.HandleFunc("/orders/{id}", GetOrder).Methods("GET")
+	r.HandleFunc("/instruments", GetInstruments).Methods("GET")
+	r.HandleFunc("/instruments/{symbol}", GetInstrument).Methods("GET")
+	r.HandleFunc("/instruments/{symbol}/fees", GetFeeSchedule).Methods("GET")
+	r.HandleFunc("/admin/instruments", CreateInstrument).Methods("POST")
+	r.HandleFunc("/admin/instruments/{symbol}", UpdateInstrument).Methods("PUT")
+	r.HandleFunc("/admin/instruments/{symbol}", DeleteInstrument).Methods("DELETE")
+	r.HandleFunc("/admin/instruments/{symbol}/fees", UpdateFeeSchedule).Methods("PUT")
+	r.HandleFunc("/admin/instruments/{symbol}/halt", HaltInstrument).Methods("POST")
+	r.HandleFunc("/admin/instruments/{symbol}/resume", ResumeInstrument).Methods("POST")
+	r.HandleFunc("/instruments/{symbol}/session", GetSession).Methods("GET")
+	r.HandleFunc("/admin/instruments/{symbol}/session", UpdateSessionSchedule).Methods("PUT")
+	r.HandleFunc("/admin/instruments/{symbol}/phase", SetPhase).Methods("POST")
+	r.HandleFunc("/account", GetAccount).Methods("GET")
+	r.HandleFunc("/account/orders", GetAccountOrders).Methods("GET")
+	r.HandleFunc("/account/balances", GetAccountBalances).Methods("GET")
+	r.HandleFunc("/account/ledger", GetAccountLedger).Methods("GET")
+	r.HandleFunc("/accounts/{id}/fees", GetAccountFees).Methods("GET")
+	r.HandleFunc("/admin/accounts", GetAccounts).Methods("GET")
+	r.HandleFunc("/admin/accounts", CreateAccount).Methods("POST")
+	r.HandleFunc("/admin/accounts/{id}/api_keys", CreateAPIKey).Methods("POST")
+	r.HandleFunc("/admin/accounts/{id}/balances", AdjustBalance).Methods("POST")
+	r.HandleFunc("/admin/accounts/{id}/ledger", GetLedger).Methods("GET")
+	r.HandleFunc("/admin/ledger/check", CheckLedger).Methods("GET")
+	r.HandleFunc("/admin/api_keys/{key}", RevokeAPIKey).Methods("DELETE")
+	r.HandleFunc("/admin/risk_limits", GetRiskLimits).Methods("GET")
+	r.HandleFunc("/admin/risk_limits", SetRiskLimits).Methods("PUT")
+	r.HandleFunc("/admin/risk_limits", DeleteRiskLimits).Methods("DELETE")
 }
 
 // CreateOrder handles POST /orders to place a new order
@@ -39,38 +74,145 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 		utils.JSONErrorResponse(w, http.StatusBadRequest, "Symbol is required")
 		return
 	}
-	if order.Quantity <= 0 {
+	if order.Quantity.Sign() <= 0 {
 		utils.JSONErrorResponse(w, http.StatusBadRequest, "Quantity must be greater than 0")
 		return
 	}
-	if order.Type == "limit" {
+	if order.Side != "buy" && order.Side != "sell" {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid side: %s, must be one of buy, sell", order.Side))
+		return
+	}
+	switch order.Type {
+	case "limit", "market", "stop", "stop_limit":
+	default:
+		utils.JSONErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid type: %s, must be one of limit, market, stop, stop_limit", order.Type))
+		return
+	}
+	if order.Type == "limit" || order.Type == "stop_limit" {
 		if order.Price == nil {
 			utils.JSONErrorResponse(w, http.StatusBadRequest, "Price is required for limit orders")
 			return
 		}
-		if *order.Price <= 0 {
+		if order.Price.Sign() <= 0 {
 			utils.JSONErrorResponse(w, http.StatusBadRequest, "Price must be greater than 0 for limit orders")
 			return
 		}
 	}
+	if order.Type == "stop" || order.Type == "stop_limit" {
+		if order.StopPrice == nil {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "Stop price is required for stop orders")
+			return
+		}
+		if order.StopPrice.Sign() <= 0 {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "Stop price must be greater than 0 for stop orders")
+			return
+		}
+	} else if order.StopPrice != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Stop price is only allowed for stop and stop_limit orders")
+		return
+	}
+	if order.Type == "stop" {
+		order.Price = nil // Triggered stops execute as market orders
+	}
+	if order.DisplayQuantity != nil {
+		if order.Type != "limit" {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "Display quantity is only allowed for limit orders")
+			return
+		}
+		if order.DisplayQuantity.Sign() <= 0 || order.DisplayQuantity.Cmp(order.Quantity) > 0 {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "Display quantity must be greater than 0 and at most the order quantity")
+			return
+		}
+	}
+
+	switch order.TimeInForce {
+	case "":
+		// Market orders never rest unless explicitly asked to
+		order.TimeInForce = engine.TimeInForceGTC
+		if order.Type == "market" || order.Type == "stop" {
+			order.TimeInForce = engine.TimeInForceIOC
+		}
+	case engine.TimeInForceGTC, engine.TimeInForceIOC, engine.TimeInForceFOK:
+	default:
+		utils.JSONErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid time_in_force: %s, must be one of GTC, IOC, FOK", order.TimeInForce))
+		return
+	}
+
+	if order.PostOnly {
+		if order.Type != "limit" || order.TimeInForce != engine.TimeInForceGTC {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "Post-only is only allowed for GTC limit orders")
+			return
+		}
+		switch order.PostOnlyMode {
+		case "":
+			order.PostOnlyMode = engine.PostOnlyReject
+		case engine.PostOnlyReject, engine.PostOnlyReprice:
+		default:
+			utils.JSONErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid post_only_mode: %s, must be one of reject, reprice", order.PostOnlyMode))
+			return
+		}
+	} else if order.PostOnlyMode != "" {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "post_only_mode requires post_only")
+		return
+	}
 
+	switch order.STPMode {
+	case "", engine.STPCancelNewest, engine.STPCancelOldest, engine.STPCancelBoth, engine.STPDecrementAndCancel:
+	default:
+		utils.JSONErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid stp_mode: %s, must be one of cancel_newest, cancel_oldest, cancel_both, decrement_and_cancel", order.STPMode))
+		return
+	}
+
+	// Enforce the trading rules of the instrument
+	if err := orderBook.Instruments.ValidateOrder(&order); err != nil {
+		writeRejectError(w, err)
+		return
+	}
+
+	order.AccountID = accountFrom(r).ID // orders always belong to the caller
+	order.ProtectionPrice = nil         // set by the engine for market orders
 	order.Status = "open"
 	order.RemainingQuantity = order.Quantity
+	order.VisibleQuantity = models.Decimal{}
 	order.CreatedAt = time.Now()
 	order.UpdatedAt = order.CreatedAt
 
-	if orderBook == nil {
-		orderBook = engine.NewOrderBook()
+	var requestedPrice models.Decimal
+	if order.Price != nil {
+		requestedPrice = *order.Price
 	}
 
 	if err := orderBook.MatchOrders(&order); err != nil {
-		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to process order")
+		writeRejectError(w, err)
 		return
 	}
 
+	resp := struct {
+		models.Order
+		PostOnlyResult *postOnlyResult `json:"post_only_result,omitempty"`
+	}{Order: order}
+	if order.PostOnly {
+		resp.PostOnlyResult = &postOnlyResult{
+			Mode:     order.PostOnlyMode,
+			Repriced: !order.Price.Equal(requestedPrice),
+			Price:    *order.Price,
+		}
+		if resp.PostOnlyResult.Repriced {
+			resp.PostOnlyResult.RequestedPrice = &requestedPrice
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(order)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// postOnlyResult explains how a post-only order was handled on submission
+type postOnlyResult struct {
+	Mode           string          `json:"mode"`
+	Repriced       bool            `json:"repriced"`
+	RequestedPrice *models.Decimal `json:"requested_price,omitempty"`
+	Price          models.Decimal  `json:"price"`
 }
 
 // CancelOrder handles DELETE /orders/{id} to cancel an order
@@ -87,7 +229,7 @@ func CancelOrder(w http.ResponseWriter, r *http.Request) {
 		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve order")
 		return
 	}
-	if order == nil {
+	if order == nil || !canAccess(accountFrom(r), order) {
 		utils.JSONErrorResponse(w, http.StatusNotFound, "Order not found")
 		return
 	}
@@ -96,7 +238,11 @@ func CancelOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := db.CancelOrder(orderID); err != nil {
+	if err := orderBook.CancelOrder(order.Symbol, orderID); err != nil {
+		if err == engine.ErrOrderNotFound {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "Order is not resting in the order book")
+			return
+		}
 		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to cancel order")
 		return
 	}
@@ -104,7 +250,89 @@ func CancelOrder(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent) // 204 No Content for successful deletion
 }
 
-// GetOrderBook handles GET /orderbook?symbol={symbol} to query the order book
+// AmendOrder handles PATCH /orders/{id} to change the price and/or quantity of a resting limit order
+func AmendOrder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	orderID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	var req struct {
+		Price    *models.Decimal `json:"price"`
+		Quantity *models.Decimal `json:"quantity"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if req.Price == nil && req.Quantity == nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "price or quantity is required")
+		return
+	}
+	if req.Price != nil && req.Price.Sign() <= 0 {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Price must be greater than 0")
+		return
+	}
+	if req.Quantity != nil && req.Quantity.Sign() <= 0 {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Quantity must be greater than 0")
+		return
+	}
+
+	order, err := db.GetOrderByID(orderID)
+	if err != nil {
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve order")
+		return
+	}
+	if order == nil || !canAccess(accountFrom(r), order) {
+		utils.JSONErrorResponse(w, http.StatusNotFound, "Order not found")
+		return
+	}
+	if order.Status == "filled" || order.Status == "canceled" {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Order cannot be amended, status is "+order.Status)
+		return
+	}
+
+	// Check the amended order against the trading rules of the instrument
+	amended := *order
+	if req.Price != nil {
+		amended.Price = req.Price
+	}
+	if req.Quantity != nil {
+		amended.Quantity = *req.Quantity
+	}
+	if err := orderBook.Instruments.ValidateOrder(&amended); err != nil {
+		writeRejectError(w, err)
+		return
+	}
+	if req.Price == nil {
+		amended.Price = nil // keep the current price
+	}
+
+	result, err := orderBook.AmendOrder(order.Symbol, orderID, amended.Price, amended.Quantity)
+	if err != nil {
+		switch err {
+		case engine.ErrOrderNotFound:
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "Order is not resting in the order book")
+		case engine.ErrAmendNotAllowed, engine.ErrAmendQuantity:
+			utils.JSONErrorResponse(w, http.StatusBadRequest, err.Error())
+		default:
+			writeRejectError(w, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// orderBookLimit is how many orders per side GET /orderbook lists unless full is set
+const orderBookLimit = 10
+
+// GetOrderBook handles GET /orderbook?symbol={symbol} to query the order book. The
+// resting orders are read from the engine's in-memory book, each side best first.
 func GetOrderBook(w http.ResponseWriter, r *http.Request) {
 	symbol := r.URL.Query().Get("symbol")
 	if symbol == "" {
@@ -115,31 +343,35 @@ func GetOrderBook(w http.ResponseWriter, r *http.Request) {
 	fullStr := r.URL.Query().Get("full")
 	full := fullStr == "true" // Default to false if not provided or invalid
 
-	orders, err := db.GetOrderBook(symbol, full)
-	if err != nil {
-		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to get order book")
-		return
-	}
-
 	var bids, asks []models.Order
-	for _, order := range orders {
-		if order.Side == "buy" {
-			bids = append(bids, order)
-		} else {
-			asks = append(asks, order)
+	state := models.TradingState{Symbol: symbol, Phase: models.PhaseContinuous}
+	if _, ok := orderBook.Instruments.Get(symbol); ok {
+		limit := orderBookLimit
+		if full {
+			limit = 0
 		}
+		bids, asks = orderBook.RestingOrders(symbol, limit)
+		state = orderBook.TradingState(symbol)
 	}
 
 	orderBookResp := struct {
-		Symbol string          `json:"symbol"`
-		Bids   []models.Order  `json:"bids"`
-		Asks   []models.Order  `json:"asks"`
-		Full   bool            `json:"full"`
+		Symbol     string          `json:"symbol"`
+		Bids       []models.Order  `json:"bids"`
+		Asks       []models.Order  `json:"asks"`
+		Full       bool            `json:"full"`
+		Phase      string          `json:"phase"`
+		Halted     bool            `json:"halted"`
+		Halt       *models.Halt    `json:"halt,omitempty"`
+		Indicative *models.Auction `json:"indicative,omitempty"`
 	}{
-		Symbol: symbol,
-		Bids:   bids,
-		Asks:   asks,
-		Full:   full,
+		Symbol:     symbol,
+		Bids:       bids,
+		Asks:       asks,
+		Full:       full,
+		Phase:      state.Phase,
+		Halted:     state.Halt != nil,
+		Halt:       state.Halt,
+		Indicative: state.Indicative,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -157,13 +389,35 @@ func GetTrades(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Callers only see their own side of a trade and its fee unless they are admins
+	if account := accountFrom(r); account == nil || !account.IsAdmin() {
+		for i := range trades {
+			if account == nil || trades[i].BuyAccountID != account.ID {
+				trades[i].BuyAccountID = 0
+				trades[i].BuyFee = models.Decimal{}
+				trades[i].BuyFeeAsset = ""
+			}
+			if account == nil || trades[i].SellAccountID != account.ID {
+				trades[i].SellAccountID = 0
+				trades[i].SellFee = models.Decimal{}
+				trades[i].SellFeeAsset = ""
+			}
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(trades)
 }
 
-// UpdateOrderStatus handles PUT /orders/{id}/status to update order status
+// UpdateOrderStatus handles PUT /orders/{id}/status to update order status.
+// It overrides the engine's bookkeeping and is restricted to admins.
 func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	if !accountFrom(r).IsAdmin() {
+		utils.JSONErrorResponse(w, http.StatusForbidden, "Admin access required")
+		return
+	}
+
 	vars := mux.Vars(r)
 	orderID, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
@@ -173,7 +427,7 @@ func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 
 	var req struct {
 		Status           string `json:"status"`
-		RemainingQuantity int    `json:"remaining_quantity"`
+		RemainingQuantity models.Decimal `json:"remaining_quantity"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
@@ -185,7 +439,7 @@ func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve order")
 		return
 	}
-	if order == nil {
+	if order == nil || !canAccess(accountFrom(r), order) {
 		utils.JSONErrorResponse(w, http.StatusNotFound, "Order not found")
 		return
 	}
@@ -198,8 +452,8 @@ func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate remaining quantity
-	if req.RemainingQuantity < 0 || req.RemainingQuantity > order.Quantity {
-		utils.JSONErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid remaining_quantity: %d, must be between 0 and original quantity %d", req.RemainingQuantity, order.Quantity))
+	if req.RemainingQuantity.Sign() < 0 || req.RemainingQuantity.Cmp(order.Quantity) > 0 {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid remaining_quantity: %s, must be between 0 and original quantity %s", req.RemainingQuantity, order.Quantity))
 		return
 	}
 
@@ -220,22 +474,26 @@ func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 	// Additional validation based on status
 	switch req.Status {
 	case "filled":
-		if req.RemainingQuantity != 0 {
+		if !req.RemainingQuantity.IsZero() {
 			utils.JSONErrorResponse(w, http.StatusBadRequest, "filled status requires remaining_quantity to be 0")
 			return
 		}
 	case "open", "partially_filled":
-		if req.RemainingQuantity == 0 {
+		if req.RemainingQuantity.IsZero() {
 			utils.JSONErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("%s status requires remaining_quantity greater than 0", req.Status))
 			return
 		}
 	}
 
-	if err := db.UpdateOrderStatus(orderID, req.Status, req.RemainingQuantity); err != nil {
-		if err == sql.ErrNoRows {
+	if err := orderBook.UpdateOrderStatus(order.Symbol, orderID, req.Status, req.RemainingQuantity); err != nil {
+		if err == engine.ErrOrderNotFound {
 			utils.JSONErrorResponse(w, http.StatusNotFound, "Order not found")
 			return
 		}
+		if rej, ok := err.(*engine.RejectError); ok {
+			utils.JSONErrorCodeResponse(w, http.StatusBadRequest, rej.Code, rej.Message)
+			return
+		}
 		utils.JSONErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -257,7 +515,7 @@ func GetOrder(w http.ResponseWriter, r *http.Request) {
 		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve order")
 		return
 	}
-	if order == nil {
+	if order == nil || !canAccess(accountFrom(r), order) {
 		utils.JSONErrorResponse(w, http.StatusNotFound, "Order not found")
 		return
 	}
@@ -265,4 +523,13 @@ func GetOrder(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(order)
-}
\ No newline at end of file
+}
+
+// writeRejectError reports an order rejection with its reason code
+func writeRejectError(w http.ResponseWriter, err error) {
+	if rej, ok := err.(*engine.RejectError); ok {
+		utils.JSONErrorCodeResponse(w, http.StatusBadRequest, rej.Code, rej.Message)
+		return
+	}
+	utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to process order")
+}
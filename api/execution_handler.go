@@ -0,0 +1,123 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang-order-matching-system/db"
+	"golang-order-matching-system/engine"
+	"golang-order-matching-system/models"
+	"golang-order-matching-system/utils"
+
+	"github.com/gorilla/websocket"
+)
+
+// replayPageSize is how many stored reports are read at a time when a client resumes
+const replayPageSize = 1000
+
+// executionMessage is a message of the execution report stream
+type executionMessage struct {
+	Type   string                  `json:"type"`          // "execution_report" or "replay_complete"
+	Seq    int64                   `json:"seq,omitempty"` // replay_complete: the last replayed sequence number
+	Report *models.ExecutionReport `json:"report,omitempty"`
+}
+
+// StreamExecutions handles GET /ws/account, a WebSocket stream of the execution
+// reports of the authenticated account: accepted, filled, canceled and rejected
+// orders with their fills. With ?since=<seq> the stored reports after seq are
+// replayed first, followed by a replay_complete message; live reports follow
+// without gaps or repeats.
+func StreamExecutions(w http.ResponseWriter, r *http.Request) {
+	since := int64(-1)
+	if s := r.URL.Query().Get("since"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "since must be a non-negative sequence number")
+			return
+		}
+		since = n
+	}
+	account := accountFrom(r)
+
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		log.Printf("Execution report upgrade failed: %v", err)
+		return
+	}
+	// Subscribe before reading the stored reports so none committed in between is missed
+	sub := orderBook.Executions.Subscribe(account.ID)
+	defer orderBook.Executions.Unsubscribe(sub)
+
+	last := since
+	if since >= 0 {
+		if last, err = replayExecutions(conn, account.ID, since); err != nil {
+			conn.close(websocket.CloseInternalServerErr, "replay failed")
+			return
+		}
+	}
+	go writeExecutions(conn, sub, last)
+
+	// Clients do not send requests; reading answers pings and notices the close
+	for {
+		if _, err := conn.readMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// replayExecutions sends the stored reports of an account after since and returns
+// the sequence number of the last one sent
+func replayExecutions(conn *wsConn, accountID, since int64) (int64, error) {
+	last := since
+	for {
+		reports, err := db.GetExecutionReports(accountID, last, replayPageSize)
+		if err != nil {
+			return last, err
+		}
+		for i := range reports {
+			if err := conn.writeJSON(executionMessage{Type: "execution_report", Report: &reports[i]}); err != nil {
+				return last, err
+			}
+			last = reports[i].Seq
+		}
+		if len(reports) < replayPageSize {
+			return last, conn.writeJSON(executionMessage{Type: "replay_complete", Seq: last})
+		}
+	}
+}
+
+// writeExecutions forwards the live reports of a subscriber after the sequence
+// number last to its connection and pings it while idle. It closes the connection
+// once the subscriber is done, with code 1008 if it was dropped for falling behind.
+func writeExecutions(conn *wsConn, sub *engine.ExecutionSubscriber, last int64) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case report := <-sub.Reports():
+			if report.Seq <= last {
+				continue // already replayed
+			}
+			if err := conn.writeJSON(executionMessage{Type: "execution_report", Report: &report}); err != nil {
+				sub.Close()
+				conn.close(websocket.CloseNormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			if err := conn.ping(); err != nil {
+				sub.Close()
+				conn.close(websocket.CloseNormalClosure, "")
+				return
+			}
+		case <-sub.Done():
+			if sub.Dropped() {
+				conn.close(websocket.ClosePolicyViolation, "slow consumer")
+			} else {
+				conn.close(websocket.CloseNormalClosure, "")
+			}
+			return
+		}
+	}
+}
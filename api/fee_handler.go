@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"golang-order-matching-system/engine"
+	"golang-order-matching-system/models"
+	"golang-order-matching-system/utils"
+	"github.com/gorilla/mux"
+)
+
+// GetAccountFees handles GET /accounts/{id}/fees to show the current fee tier of
+// an account on every instrument, or on the one given by ?symbol=. Accounts may
+// only see their own fees unless they are admins.
+func GetAccountFees(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+	if account := accountFrom(r); !account.IsAdmin() && account.ID != accountID {
+		utils.JSONErrorResponse(w, http.StatusNotFound, "Account not found")
+		return
+	}
+
+	var symbols []string
+	if symbol := r.URL.Query().Get("symbol"); symbol != "" {
+		if _, ok := orderBook.Instruments.Get(symbol); !ok {
+			utils.JSONErrorResponse(w, http.StatusNotFound, "Instrument not found")
+			return
+		}
+		symbols = append(symbols, symbol)
+	} else {
+		for _, inst := range orderBook.Instruments.List() {
+			symbols = append(symbols, inst.Symbol)
+		}
+	}
+
+	fees := make([]*models.AccountFees, 0, len(symbols))
+	for _, symbol := range symbols {
+		fees = append(fees, orderBook.Fees.Fees(accountID, symbol))
+	}
+	utils.JSONResponse(w, http.StatusOK, fees)
+}
+
+// GetFeeSchedule handles GET /instruments/{symbol}/fees to retrieve the fee schedule of an instrument
+func GetFeeSchedule(w http.ResponseWriter, r *http.Request) {
+	symbol := mux.Vars(r)["symbol"]
+	if _, ok := orderBook.Instruments.Get(symbol); !ok {
+		utils.JSONErrorResponse(w, http.StatusNotFound, "Instrument not found")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, orderBook.Fees.Schedule(symbol))
+}
+
+// UpdateFeeSchedule handles PUT /admin/instruments/{symbol}/fees to replace the
+// fee schedule of an instrument. An empty list makes trading free.
+func UpdateFeeSchedule(w http.ResponseWriter, r *http.Request) {
+	symbol := mux.Vars(r)["symbol"]
+	if _, ok := orderBook.Instruments.Get(symbol); !ok {
+		utils.JSONErrorResponse(w, http.StatusNotFound, "Instrument not found")
+		return
+	}
+
+	var tiers []models.FeeTier
+	if err := json.NewDecoder(r.Body).Decode(&tiers); err != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := orderBook.Fees.Set(symbol, tiers); err != nil {
+		if errors.Is(err, engine.ErrInvalidFeeSchedule) {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to save fee schedule")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, orderBook.Fees.Schedule(symbol))
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"golang-order-matching-system/engine"
+	"golang-order-matching-system/models"
+	"golang-order-matching-system/utils"
+	"github.com/gorilla/mux"
+)
+
+// GetInstruments handles GET /instruments to list all instruments
+func GetInstruments(w http.ResponseWriter, r *http.Request) {
+	utils.JSONResponse(w, http.StatusOK, orderBook.Instruments.List())
+}
+
+// GetInstrument handles GET /instruments/{symbol} to retrieve one instrument
+func GetInstrument(w http.ResponseWriter, r *http.Request) {
+	inst, ok := orderBook.Instruments.Get(mux.Vars(r)["symbol"])
+	if !ok {
+		utils.JSONErrorResponse(w, http.StatusNotFound, "Instrument not found")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, inst)
+}
+
+// CreateInstrument handles POST /admin/instruments to register a new instrument
+func CreateInstrument(w http.ResponseWriter, r *http.Request) {
+	var inst models.Instrument
+	if err := json.NewDecoder(r.Body).Decode(&inst); err != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := orderBook.Instruments.Create(&inst); err != nil {
+		if err == engine.ErrInstrumentExists {
+			utils.JSONErrorResponse(w, http.StatusConflict, "Instrument already exists")
+			return
+		}
+		writeInstrumentError(w, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusCreated, inst)
+}
+
+// UpdateInstrument handles PUT /admin/instruments/{symbol} to replace the trading rules of an instrument
+func UpdateInstrument(w http.ResponseWriter, r *http.Request) {
+	var inst models.Instrument
+	if err := json.NewDecoder(r.Body).Decode(&inst); err != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	inst.Symbol = mux.Vars(r)["symbol"]
+
+	if err := orderBook.Instruments.Update(&inst); err != nil {
+		writeInstrumentError(w, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, inst)
+}
+
+// DeleteInstrument handles DELETE /admin/instruments/{symbol} to remove an instrument without open orders
+func DeleteInstrument(w http.ResponseWriter, r *http.Request) {
+	symbol := mux.Vars(r)["symbol"]
+	if _, ok := orderBook.Instruments.Get(symbol); !ok {
+		utils.JSONErrorResponse(w, http.StatusNotFound, "Instrument not found")
+		return
+	}
+	if orderBook.OpenOrderCount(symbol) > 0 {
+		utils.JSONErrorResponse(w, http.StatusConflict, "Instrument has open orders")
+		return
+	}
+
+	if err := orderBook.Instruments.Delete(symbol); err != nil {
+		writeInstrumentError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HaltInstrument handles POST /admin/instruments/{symbol}/halt to stop matching in
+// a symbol until it is resumed
+func HaltInstrument(w http.ResponseWriter, r *http.Request) {
+	symbol := mux.Vars(r)["symbol"]
+	if _, ok := orderBook.Instruments.Get(symbol); !ok {
+		utils.JSONErrorResponse(w, http.StatusNotFound, "Instrument not found")
+		return
+	}
+
+	var req struct {
+		Message string `json:"message"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+	}
+	if len(req.Message) > 255 {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Message must be at most 255 characters")
+		return
+	}
+
+	halt, err := orderBook.HaltSymbol(symbol, req.Message)
+	if err != nil {
+		if err == engine.ErrSymbolHalted {
+			utils.JSONErrorResponse(w, http.StatusConflict, "Instrument is already halted")
+			return
+		}
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to halt instrument")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, halt)
+}
+
+// ResumeInstrument handles POST /admin/instruments/{symbol}/resume to end a halt
+// with a reopening auction
+func ResumeInstrument(w http.ResponseWriter, r *http.Request) {
+	symbol := mux.Vars(r)["symbol"]
+	if _, ok := orderBook.Instruments.Get(symbol); !ok {
+		utils.JSONErrorResponse(w, http.StatusNotFound, "Instrument not found")
+		return
+	}
+
+	halt, err := orderBook.ResumeSymbol(symbol)
+	if err != nil {
+		if err == engine.ErrSymbolNotHalted {
+			utils.JSONErrorResponse(w, http.StatusConflict, "Instrument is not halted")
+			return
+		}
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to resume instrument")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, halt)
+}
+
+// writeInstrumentError maps registry errors to HTTP responses
+func writeInstrumentError(w http.ResponseWriter, err error) {
+	switch {
+	case err == engine.ErrInstrumentNotFound:
+		utils.JSONErrorResponse(w, http.StatusNotFound, "Instrument not found")
+	case errors.Is(err, engine.ErrInvalidInstrument):
+		utils.JSONErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to save instrument")
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"golang-order-matching-system/db"
+	"golang-order-matching-system/utils"
+	"github.com/gorilla/mux"
+)
+
+// defaultLedgerLimit and maxLedgerLimit bound the entries returned by one ledger request
+const (
+	defaultLedgerLimit = 100
+	maxLedgerLimit     = 1000
+)
+
+// GetAccountLedger handles GET /account/ledger?asset=&before=&limit= to page
+// through the ledger entries of the authenticated account, newest first
+func GetAccountLedger(w http.ResponseWriter, r *http.Request) {
+	writeLedger(w, r, accountFrom(r).ID)
+}
+
+// GetLedger handles GET /admin/accounts/{id}/ledger to page through the ledger entries of any account
+func GetLedger(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+	writeLedger(w, r, accountID)
+}
+
+// CheckLedger handles GET /admin/ledger/check to verify that the ledger sums to
+// zero per asset and per journal and that balances match their entries
+func CheckLedger(w http.ResponseWriter, r *http.Request) {
+	check, err := db.CheckLedger()
+	if err != nil {
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to check ledger")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, check)
+}
+
+// writeLedger writes one page of the ledger entries of an account
+func writeLedger(w http.ResponseWriter, r *http.Request, accountID int64) {
+	query := r.URL.Query()
+	limit := defaultLedgerLimit
+	if s := query.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxLedgerLimit {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		limit = n
+	}
+	var before int64
+	if s := query.Get("before"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n <= 0 {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "before must be a positive entry ID")
+			return
+		}
+		before = n
+	}
+
+	entries, err := db.GetLedgerEntries(accountID, query.Get("asset"), before, limit)
+	if err != nil {
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to get ledger")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, entries)
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang-order-matching-system/engine"
+	"golang-order-matching-system/models"
+	"golang-order-matching-system/utils"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// maxSubscriptions bounds the symbol channels one market data connection may follow
+	maxSubscriptions = 100
+	// defaultDepth and maxDepth bound the price levels per side of a depth request
+	defaultDepth = 10
+	maxDepth     = 1000
+)
+
+// marketDataRequest is a message of a market data client
+type marketDataRequest struct {
+	Op       string   `json:"op"` // "subscribe" or "unsubscribe"
+	Symbol   string   `json:"symbol"`
+	Channels []string `json:"channels"` // "depth", "trades" and/or "bbo"
+}
+
+// marketDataReply acknowledges a request or reports why it was refused
+type marketDataReply struct {
+	Type     string   `json:"type"` // "subscribed", "unsubscribed" or "error"
+	Symbol   string   `json:"symbol,omitempty"`
+	Channels []string `json:"channels,omitempty"`
+	Message  string   `json:"message,omitempty"`
+}
+
+// subscription is a channel of a symbol a connection follows
+type subscription struct {
+	symbol  string
+	channel string
+}
+
+// StreamMarketData handles GET /ws/market, a WebSocket feed of market data. Clients
+// send {"op":"subscribe","symbol":"AAPL","channels":["depth","trades","bbo"]} and
+// receive a depth snapshot followed by incremental updates, public trades and best
+// bid and offer changes. Connections that fall too far behind are closed with code
+// 1008 instead of slowing down matching.
+func StreamMarketData(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		log.Printf("Market data upgrade failed: %v", err)
+		return
+	}
+	sub := engine.NewSubscriber()
+	go writeMarketData(conn, sub)
+
+	subscriptions := make(map[subscription]bool)
+	defer func() {
+		sub.Close()
+		for s := range subscriptions {
+			orderBook.Unsubscribe(sub, s.symbol, s.channel)
+		}
+	}()
+
+	for {
+		data, err := conn.readMessage()
+		if err != nil {
+			return
+		}
+		var req marketDataRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			conn.writeJSON(marketDataReply{Type: "error", Message: "Invalid request payload"})
+			continue
+		}
+		if err := validateMarketDataRequest(req, subscriptions); err != nil {
+			conn.writeJSON(marketDataReply{Type: "error", Symbol: req.Symbol, Message: err.Error()})
+			continue
+		}
+
+		if req.Op == "unsubscribe" {
+			for _, channel := range req.Channels {
+				orderBook.Unsubscribe(sub, req.Symbol, channel)
+				delete(subscriptions, subscription{req.Symbol, channel})
+			}
+			conn.writeJSON(marketDataReply{Type: "unsubscribed", Symbol: req.Symbol, Channels: req.Channels})
+			continue
+		}
+		// The acknowledgement goes out before the snapshot the subscription queues
+		conn.writeJSON(marketDataReply{Type: "subscribed", Symbol: req.Symbol, Channels: req.Channels})
+		for _, channel := range req.Channels {
+			if subscriptions[subscription{req.Symbol, channel}] {
+				continue
+			}
+			if err := orderBook.Subscribe(sub, req.Symbol, channel); err != nil {
+				conn.writeJSON(marketDataReply{Type: "error", Symbol: req.Symbol, Message: err.Error()})
+				continue
+			}
+			subscriptions[subscription{req.Symbol, channel}] = true
+		}
+	}
+}
+
+// validateMarketDataRequest checks the operation, symbol and channels of a request
+func validateMarketDataRequest(req marketDataRequest, subscriptions map[subscription]bool) error {
+	if req.Op != "subscribe" && req.Op != "unsubscribe" {
+		return fmt.Errorf("op must be subscribe or unsubscribe")
+	}
+	if _, ok := orderBook.Instruments.Get(req.Symbol); !ok {
+		return fmt.Errorf("unknown symbol %q", req.Symbol)
+	}
+	if len(req.Channels) == 0 {
+		return fmt.Errorf("channels are required")
+	}
+	for _, channel := range req.Channels {
+		switch channel {
+		case models.ChannelDepth, models.ChannelTrades, models.ChannelBBO:
+		default:
+			return fmt.Errorf("unknown channel %q, expected depth, trades or bbo", channel)
+		}
+	}
+	if req.Op == "subscribe" && len(subscriptions)+len(req.Channels) > maxSubscriptions {
+		return fmt.Errorf("at most %d subscriptions per connection", maxSubscriptions)
+	}
+	return nil
+}
+
+// writeMarketData forwards the events of a subscriber to its connection and pings
+// it while idle. It closes the connection once the subscriber is done, with code
+// 1008 if the subscriber was dropped for falling behind.
+func writeMarketData(conn *wsConn, sub *engine.Subscriber) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event := <-sub.Events():
+			if err := conn.writeJSON(event); err != nil {
+				sub.Close()
+				conn.close(websocket.CloseNormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			if err := conn.ping(); err != nil {
+				sub.Close()
+				conn.close(websocket.CloseNormalClosure, "")
+				return
+			}
+		case <-sub.Done():
+			if sub.Dropped() {
+				conn.close(websocket.ClosePolicyViolation, "slow consumer")
+			} else {
+				conn.close(websocket.CloseNormalClosure, "")
+			}
+			return
+		}
+	}
+}
+
+// GetDepth handles GET /orderbook/depth?symbol={symbol}&depth={n}&group={bucket}, the
+// aggregated price levels of both sides with their visible quantity and order
+// count, best first. It is served from the engine's book, not the database.
+func GetDepth(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	symbol := query.Get("symbol")
+	if symbol == "" {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Symbol is required")
+		return
+	}
+	inst, ok := orderBook.Instruments.Get(symbol)
+	if !ok {
+		utils.JSONErrorResponse(w, http.StatusNotFound, fmt.Sprintf("Unknown symbol: %s", symbol))
+		return
+	}
+
+	depth := defaultDepth
+	if s := query.Get("depth"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxDepth {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("depth must be between 1 and %d", maxDepth))
+			return
+		}
+		depth = n
+	}
+	var group models.Decimal
+	if s := query.Get("group"); s != "" {
+		g, err := models.ParseDecimal(s)
+		if err != nil || g.Sign() <= 0 || !g.Mod(inst.TickSize).IsZero() {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("group must be a positive multiple of the tick size %s", inst.TickSize))
+			return
+		}
+		group = g
+	}
+
+	utils.JSONResponse(w, http.StatusOK, orderBook.Depth(symbol, depth, group))
+}
+
+// GetBookSnapshot handles GET /orderbook/l3?symbol={symbol}, every resting order
+// with its visible quantity and queue position per level in priority order, and
+// the engine sequence number the snapshot was taken at. Owners are not shown.
+func GetBookSnapshot(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Symbol is required")
+		return
+	}
+	if _, ok := orderBook.Instruments.Get(symbol); !ok {
+		utils.JSONErrorResponse(w, http.StatusNotFound, fmt.Sprintf("Unknown symbol: %s", symbol))
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, orderBook.Snapshot(symbol))
+}
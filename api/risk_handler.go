@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"golang-order-matching-system/db"
+	"golang-order-matching-system/engine"
+	"golang-order-matching-system/models"
+	"golang-order-matching-system/utils"
+)
+
+// GetRiskLimits handles GET /admin/risk_limits to list all configured risk limits
+func GetRiskLimits(w http.ResponseWriter, r *http.Request) {
+	utils.JSONResponse(w, http.StatusOK, orderBook.Risk.List())
+}
+
+// SetRiskLimits handles PUT /admin/risk_limits to create or replace the risk limits
+// of an account (account_id), an instrument (symbol) or an account on an instrument
+func SetRiskLimits(w http.ResponseWriter, r *http.Request) {
+	var limits models.RiskLimits
+	if err := json.NewDecoder(r.Body).Decode(&limits); err != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if limits.Symbol != "" {
+		if _, ok := orderBook.Instruments.Get(limits.Symbol); !ok {
+			utils.JSONErrorResponse(w, http.StatusNotFound, "Instrument not found")
+			return
+		}
+	}
+	if limits.AccountID > 0 {
+		account, err := db.GetAccount(limits.AccountID)
+		if err != nil {
+			utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve account")
+			return
+		}
+		if account == nil {
+			utils.JSONErrorResponse(w, http.StatusNotFound, "Account not found")
+			return
+		}
+	}
+
+	if err := orderBook.Risk.Set(&limits); err != nil {
+		if errors.Is(err, engine.ErrInvalidRiskLimits) {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to save risk limits")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, limits)
+}
+
+// DeleteRiskLimits handles DELETE /admin/risk_limits?account_id={id}&symbol={symbol}
+// to remove the risk limits of a scope. Omitted parameters select all accounts or instruments.
+func DeleteRiskLimits(w http.ResponseWriter, r *http.Request) {
+	var accountID int64
+	if v := r.URL.Query().Get("account_id"); v != "" {
+		var err error
+		if accountID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid account ID")
+			return
+		}
+	}
+
+	if err := orderBook.Risk.Delete(accountID, r.URL.Query().Get("symbol")); err != nil {
+		if err == engine.ErrRiskLimitsNotFound {
+			utils.JSONErrorResponse(w, http.StatusNotFound, "Risk limits not found")
+			return
+		}
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to delete risk limits")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
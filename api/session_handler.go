@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"golang-order-matching-system/engine"
+	"golang-order-matching-system/models"
+	"golang-order-matching-system/utils"
+	"github.com/gorilla/mux"
+)
+
+// sessionResponse is the trading phase of a symbol together with its daily session schedule
+type sessionResponse struct {
+	models.TradingState
+	Schedule []models.SessionPhase `json:"schedule"`
+}
+
+// GetSession handles GET /instruments/{symbol}/session to show the current phase
+// of a symbol, its indicative auction while orders accumulate, and its schedule
+func GetSession(w http.ResponseWriter, r *http.Request) {
+	symbol := mux.Vars(r)["symbol"]
+	if _, ok := orderBook.Instruments.Get(symbol); !ok {
+		utils.JSONErrorResponse(w, http.StatusNotFound, "Instrument not found")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, sessionResponse{
+		TradingState: orderBook.TradingState(symbol),
+		Schedule:     orderBook.Sessions.Schedule(symbol),
+	})
+}
+
+// UpdateSessionSchedule handles PUT /admin/instruments/{symbol}/session to replace
+// the daily phase schedule of a symbol
+func UpdateSessionSchedule(w http.ResponseWriter, r *http.Request) {
+	symbol := mux.Vars(r)["symbol"]
+	if _, ok := orderBook.Instruments.Get(symbol); !ok {
+		utils.JSONErrorResponse(w, http.StatusNotFound, "Instrument not found")
+		return
+	}
+
+	var schedule []models.SessionPhase
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := orderBook.Sessions.Set(symbol, schedule); err != nil {
+		if errors.Is(err, engine.ErrInvalidSessionSchedule) {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to save session schedule")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, orderBook.Sessions.Schedule(symbol))
+}
+
+// SetPhase handles POST /admin/instruments/{symbol}/phase to move a symbol to
+// another trading phase by hand. The phase lasts until the next scheduled change.
+func SetPhase(w http.ResponseWriter, r *http.Request) {
+	symbol := mux.Vars(r)["symbol"]
+	if _, ok := orderBook.Instruments.Get(symbol); !ok {
+		utils.JSONErrorResponse(w, http.StatusNotFound, "Instrument not found")
+		return
+	}
+
+	var req struct {
+		Phase string `json:"phase"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	auction, err := orderBook.SetPhase(symbol, req.Phase)
+	if err != nil {
+		if err == engine.ErrInvalidPhase {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "Phase must be one of pre_open, auction, continuous or closed")
+			return
+		}
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to change trading phase")
+		return
+	}
+
+	resp := struct {
+		models.TradingState
+		Auction *models.Auction `json:"auction,omitempty"` // uncross run by leaving a call phase
+	}{
+		TradingState: orderBook.TradingState(symbol),
+		Auction:      auction,
+	}
+	utils.JSONResponse(w, http.StatusOK, resp)
+}
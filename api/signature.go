@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang-order-matching-system/utils"
+)
+
+// Headers of a signed request
+const (
+	TimestampHeader = "X-Timestamp" // Unix time in milliseconds
+	NonceHeader     = "X-Nonce"
+	SignatureHeader = "X-Signature" // hex HMAC-SHA256 of the signing payload with the API key secret
+)
+
+// Reject codes for requests that fail signature verification
+const (
+	RejectSignatureMissing = "SIGNATURE_MISSING"
+	RejectSignatureInvalid = "SIGNATURE_INVALID"
+	RejectTimestampStale   = "TIMESTAMP_STALE"
+	RejectNonceReused      = "NONCE_REUSED"
+)
+
+const (
+	// signatureWindow is how far the timestamp of a signed request may be from the server clock
+	signatureWindow = 30 * time.Second
+	// maxNonceLength bounds the nonces kept in the replay cache
+	maxNonceLength = 64
+	// maxSignedBodySize bounds the request bodies read for verification
+	maxSignedBodySize = 1 << 20
+)
+
+// nonces remembers the nonces seen within the signature window
+var nonces = newNonceCache()
+
+// VerifySignature is a router middleware that requires every mutating request to
+// be signed with the secret of its API key. The signature covers the method,
+// request URI, timestamp, nonce and body; stale timestamps and reused nonces
+// are refused so a captured request cannot be replayed.
+func VerifySignature(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := apiKeyFrom(r)
+		timestamp := r.Header.Get(TimestampHeader)
+		nonce := r.Header.Get(NonceHeader)
+		signature := r.Header.Get(SignatureHeader)
+		if key == nil || timestamp == "" || nonce == "" || signature == "" {
+			utils.JSONErrorCodeResponse(w, http.StatusUnauthorized, RejectSignatureMissing, "Mutating requests require X-Timestamp, X-Nonce and X-Signature headers")
+			return
+		}
+		if len(nonce) > maxNonceLength {
+			utils.JSONErrorCodeResponse(w, http.StatusBadRequest, RejectSignatureInvalid, "Nonce must be at most 64 characters")
+			return
+		}
+
+		ms, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			utils.JSONErrorCodeResponse(w, http.StatusBadRequest, RejectSignatureInvalid, "Invalid X-Timestamp, must be Unix time in milliseconds")
+			return
+		}
+		now := time.Now()
+		if skew := now.Sub(time.UnixMilli(ms)); skew > signatureWindow || skew < -signatureWindow {
+			utils.JSONErrorCodeResponse(w, http.StatusUnauthorized, RejectTimestampStale, "Request timestamp is outside the accepted window")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				utils.JSONErrorResponse(w, http.StatusRequestEntityTooLarge, "Request body must be at most 1 MB")
+				return
+			}
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		expected := sign(key.Secret, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+		given, err := hex.DecodeString(signature)
+		if err != nil || !hmac.Equal(given, expected) {
+			utils.JSONErrorCodeResponse(w, http.StatusUnauthorized, RejectSignatureInvalid, "Request signature does not match")
+			return
+		}
+
+		// Only remember nonces of authentic requests so forged ones cannot burn them
+		if !nonces.add(key.Key+":"+nonce, now) {
+			utils.JSONErrorCodeResponse(w, http.StatusUnauthorized, RejectNonceReused, "Nonce has already been used")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sign returns the HMAC-SHA256 of the signing payload: method, request URI,
+// timestamp and nonce on separate lines, followed by the raw body
+func sign(secret, method, uri, timestamp, nonce string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	io.WriteString(mac, method+"\n"+uri+"\n"+timestamp+"\n"+nonce+"\n")
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// nonceCache records recently used nonces until they fall out of the signature window
+type nonceCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+func newNonceCache() *nonceCache {
+	return &nonceCache{seen: make(map[string]time.Time)}
+}
+
+// add records a nonce, reporting false if it was already used within the window
+func (c *nonceCache) add(nonce string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastPrune) > signatureWindow {
+		for n, seenAt := range c.seen {
+			// Timestamps are accepted up to one window in either direction
+			if now.Sub(seenAt) > 2*signatureWindow {
+				delete(c.seen, n)
+			}
+		}
+		c.lastPrune = now
+	}
+
+	if _, ok := c.seen[nonce]; ok {
+		return false
+	}
+	c.seen[nonce] = now
+	return true
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang-order-matching-system/db"
+	"golang-order-matching-system/engine"
+	"golang-order-matching-system/models"
+	"golang-order-matching-system/utils"
+)
+
+// sseKeepAliveInterval is how often an idle event stream receives a comment line
+const sseKeepAliveInterval = 15 * time.Second
+
+// sseStream writes Server-Sent Events and flushes each one to the client
+type sseStream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// writeEvent sends one event with an optional ID and v as JSON data
+func (s *sseStream) writeEvent(id, event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.rc.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if id != "" {
+		if _, err := fmt.Fprintf(s.w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// keepAlive sends a comment line so proxies keep an idle stream open
+func (s *sseStream) keepAlive() error {
+	s.rc.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if _, err := fmt.Fprint(s.w, ": keep-alive\n\n"); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// writeTrade sends a public trade with its ID as the event ID
+func (s *sseStream) writeTrade(trade *models.Trade) error {
+	id := ""
+	if trade.ID > 0 {
+		id = strconv.Itoa(trade.ID)
+	}
+	return s.writeEvent(id, "trade", trade)
+}
+
+// StreamTrades handles GET /stream/trades?symbol=, a Server-Sent Events tape of the
+// public trades of a symbol as they are committed. It follows the trades channel of
+// the market data feed; a client reconnecting with a Last-Event-ID header first
+// receives the trades after that trade ID from the database.
+func StreamTrades(w http.ResponseWriter, r *http.Request) {
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		utils.JSONErrorResponse(w, http.StatusBadRequest, "Symbol is required")
+		return
+	}
+	if _, ok := orderBook.Instruments.Get(symbol); !ok {
+		utils.JSONErrorResponse(w, http.StatusNotFound, fmt.Sprintf("Unknown symbol: %s", symbol))
+		return
+	}
+	last := int64(-1)
+	if s := r.Header.Get("Last-Event-ID"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			utils.JSONErrorResponse(w, http.StatusBadRequest, "Last-Event-ID must be a trade ID")
+			return
+		}
+		last = n
+	}
+
+	// Subscribe before reading stored trades; the sequencer takes the subscription
+	// between commands, so every later trade arrives live
+	sub := engine.NewSubscriber()
+	defer func() {
+		sub.Close()
+		orderBook.Unsubscribe(sub, symbol, models.ChannelTrades)
+	}()
+	if err := orderBook.Subscribe(sub, symbol, models.ChannelTrades); err != nil {
+		utils.JSONErrorResponse(w, http.StatusInternalServerError, "Failed to subscribe to trades")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	stream := &sseStream{w: w, rc: http.NewResponseController(w)}
+	if err := stream.rc.Flush(); err != nil {
+		log.Printf("Trade stream for %s cannot be flushed: %v", symbol, err)
+		return
+	}
+
+	if last >= 0 {
+		var err error
+		if last, err = replayTrades(stream, symbol, last); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(sseKeepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event := <-sub.Events():
+			if event.Trade == nil || event.Trade.ID > 0 && int64(event.Trade.ID) <= last {
+				continue // already replayed
+			}
+			if err := stream.writeTrade(event.Trade); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := stream.keepAlive(); err != nil {
+				return
+			}
+		case <-sub.Done():
+			if sub.Dropped() {
+				log.Printf("Trade stream for %s closed, the client fell behind", symbol)
+			}
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// replayTrades sends the stored trades of a symbol after the trade ID last and
+// returns the ID of the last one sent
+func replayTrades(stream *sseStream, symbol string, last int64) (int64, error) {
+	for {
+		trades, err := db.GetTradesAfter(symbol, last, replayPageSize)
+		if err != nil {
+			return last, err
+		}
+		for i := range trades {
+			if err := stream.writeTrade(engine.PublicTrade(&trades[i])); err != nil {
+				return last, err
+			}
+			last = int64(trades[i].ID)
+		}
+		if len(trades) < replayPageSize {
+			return last, nil
+		}
+	}
+}
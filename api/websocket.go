@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsMaxMessageSize bounds the messages a client may send
+	wsMaxMessageSize = 64 << 10
+	// wsWriteTimeout bounds how long a single message may take to write
+	wsWriteTimeout = 10 * time.Second
+	// wsPingInterval is how often idle connections are pinged
+	wsPingInterval = 30 * time.Second
+)
+
+// wsUpgrader performs the opening handshake. Any origin may connect: the streams
+// authenticate with the API key header rather than cookies a page could reuse.
+var wsUpgrader = websocket.Upgrader{
+	HandshakeTimeout: wsWriteTimeout,
+	CheckOrigin:      func(r *http.Request) bool { return true },
+}
+
+// wsConn is a server side WebSocket connection. Reads happen on one goroutine;
+// writes may come from several and are serialized.
+type wsConn struct {
+	conn   *websocket.Conn
+	mu     sync.Mutex // guards writes and closed
+	closed bool
+}
+
+// upgradeWebSocket performs the opening handshake and takes over the connection.
+// On failure an HTTP error has already been written.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetReadLimit(wsMaxMessageSize)
+	return &wsConn{conn: conn}, nil
+}
+
+// readMessage returns the next text or binary message of the peer. Pings are
+// answered and close frames echoed by the connection; once the peer closed it or
+// broke the protocol an error is returned.
+func (c *wsConn) readMessage() ([]byte, error) {
+	_, message, err := c.conn.ReadMessage()
+	return message, err
+}
+
+// writeJSON sends v as a text message
+func (c *wsConn) writeJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return websocket.ErrCloseSent
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return c.conn.WriteJSON(v)
+}
+
+// ping sends a ping frame to keep the connection alive
+func (c *wsConn) ping() error {
+	return c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+}
+
+// close sends a close frame with code and reason and closes the connection. It is safe to call more than once.
+func (c *wsConn) close(code int, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteTimeout))
+	c.closed = true
+	c.conn.Close()
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+
+	"golang-order-matching-system/models"
+)
+
+// accountColumns lists the columns read by scanAccount, in scan order
+const accountColumns = `a.id, a.name, a.role, a.status, a.created_at`
+
+// CreateAccount inserts a new account
+func CreateAccount(account *models.Account) error {
+	query := `
+		INSERT INTO accounts (name, role, status, created_at)
+		VALUES (?, ?, ?, ?)`
+	result, err := DB.Exec(query, account.Name, account.Role, account.Status, account.CreatedAt)
+	if err != nil {
+		log.Printf("Failed to create account: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Printf("Failed to get last insert ID: %v", err)
+		return err
+	}
+	account.ID = id
+	return nil
+}
+
+// GetAccount retrieves an account by its ID
+func GetAccount(accountID int64) (*models.Account, error) {
+	query := `SELECT ` + accountColumns + ` FROM accounts a WHERE a.id = ?`
+	account, err := scanAccount(DB.QueryRow(query, accountID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		log.Printf("Failed to get account: %v", err)
+		return nil, err
+	}
+	return account, nil
+}
+
+// GetAccounts retrieves all accounts ordered by ID
+func GetAccounts() ([]models.Account, error) {
+	var accounts []models.Account
+	rows, err := DB.Query(`SELECT ` + accountColumns + ` FROM accounts a ORDER BY a.id`)
+	if err != nil {
+		log.Printf("Failed to get accounts: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			log.Printf("Failed to scan account: %v", err)
+			return nil, err
+		}
+		accounts = append(accounts, *account)
+	}
+	return accounts, rows.Err()
+}
+
+// CreateAPIKey inserts a new API key for an account
+func CreateAPIKey(key *models.APIKey) error {
+	query := `
+		INSERT INTO api_keys (account_id, api_key, secret, revoked, created_at)
+		VALUES (?, ?, ?, FALSE, ?)`
+	result, err := DB.Exec(query, key.AccountID, key.Key, key.Secret, key.CreatedAt)
+	if err != nil {
+		log.Printf("Failed to create API key: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Printf("Failed to get last insert ID: %v", err)
+		return err
+	}
+	key.ID = id
+	return nil
+}
+
+// RevokeAPIKey disables an API key, returning sql.ErrNoRows if it does not exist
+func RevokeAPIKey(apiKey string) error {
+	result, err := DB.Exec(`UPDATE api_keys SET revoked = TRUE WHERE api_key = ?`, apiKey)
+	if err != nil {
+		log.Printf("Failed to revoke API key: %v", err)
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// HasAdminAPIKey reports whether an active admin account holds an unrevoked API key
+func HasAdminAPIKey() (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM api_keys k JOIN accounts a ON a.id = k.account_id
+			WHERE a.role = ? AND a.status = ? AND NOT k.revoked)`
+	var exists bool
+	if err := DB.QueryRow(query, models.AccountRoleAdmin, models.AccountStatusActive).Scan(&exists); err != nil {
+		log.Printf("Failed to check admin API keys: %v", err)
+		return false, err
+	}
+	return exists, nil
+}
+
+// GetAccountByAPIKey retrieves an unrevoked API key and the account it belongs to,
+// or nil if the key is unknown or revoked
+func GetAccountByAPIKey(apiKey string) (*models.Account, *models.APIKey, error) {
+	query := `
+		SELECT ` + accountColumns + `, k.id, k.api_key, k.secret
+		FROM api_keys k JOIN accounts a ON a.id = k.account_id
+		WHERE k.api_key = ? AND NOT k.revoked`
+	account := &models.Account{}
+	key := &models.APIKey{}
+	var createdAtBytes []byte
+	err := DB.QueryRow(query, apiKey).Scan(
+		&account.ID,
+		&account.Name,
+		&account.Role,
+		&account.Status,
+		&createdAtBytes,
+		&key.ID,
+		&key.Key,
+		&key.Secret)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	} else if err != nil {
+		log.Printf("Failed to get API key: %v", err)
+		return nil, nil, err
+	}
+	account.CreatedAt, err = parseTime(createdAtBytes)
+	if err != nil {
+		log.Printf("Failed to parse created_at: %v", err)
+		return nil, nil, err
+	}
+	key.AccountID = account.ID
+	return account, key, nil
+}
+
+// scanAccount reads one account selected with accountColumns
+func scanAccount(row rowScanner) (*models.Account, error) {
+	account := &models.Account{}
+	var createdAtBytes []byte
+	if err := row.Scan(&account.ID, &account.Name, &account.Role, &account.Status, &createdAtBytes); err != nil {
+		return nil, err
+	}
+	var err error
+	account.CreatedAt, err = parseTime(createdAtBytes)
+	if err != nil {
+		log.Printf("Failed to parse created_at: %v", err)
+		return nil, err
+	}
+	return account, nil
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"golang-order-matching-system/models"
+)
+
+// ErrInsufficientFunds is returned when a balance change would make the available
+// or held amount negative
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// adjustBalanceTx adds the given amounts to the available and held funds of an
+// account within a transaction. Credits create the balance row if needed; a
+// change that would leave either amount negative fails with ErrInsufficientFunds.
+// Balances only change through PostJournalTx so that the ledger stays complete.
+func adjustBalanceTx(accountID int64, asset string, available, held models.Decimal, tx *sql.Tx) error {
+	if available.IsZero() && held.IsZero() {
+		return nil
+	}
+	exec := tx.Exec
+	now := time.Now()
+
+	if available.Sign() >= 0 && held.Sign() >= 0 {
+		query := `
+			INSERT INTO balances (account_id, asset, available, held, updated_at)
+			VALUES (?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE available = available + VALUES(available), held = held + VALUES(held), updated_at = VALUES(updated_at)`
+		if _, err := exec(query, accountID, asset, available, held, now); err != nil {
+			log.Printf("Failed to credit balance: %v", err)
+			return err
+		}
+		return nil
+	}
+
+	query := `
+		UPDATE balances
+		SET available = available + CAST(? AS DECIMAL(30,8)), held = held + CAST(? AS DECIMAL(30,8)), updated_at = ?
+		WHERE account_id = ? AND asset = ?
+			AND available + CAST(? AS DECIMAL(30,8)) >= 0 AND held + CAST(? AS DECIMAL(30,8)) >= 0`
+	result, err := exec(query, available, held, now, accountID, asset, available, held)
+	if err != nil {
+		log.Printf("Failed to adjust balance: %v", err)
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrInsufficientFunds
+	}
+	return nil
+}
+
+// GetBalances retrieves the balances of an account ordered by asset
+func GetBalances(accountID int64) ([]models.Balance, error) {
+	var balances []models.Balance
+	query := `
+		SELECT account_id, asset, available, held, updated_at
+		FROM balances WHERE account_id = ? ORDER BY asset`
+	rows, err := DB.Query(query, accountID)
+	if err != nil {
+		log.Printf("Failed to get balances: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var balance models.Balance
+		var updatedAtBytes []byte
+		if err := rows.Scan(&balance.AccountID, &balance.Asset, &balance.Available, &balance.Held, &updatedAtBytes); err != nil {
+			log.Printf("Failed to scan balance: %v", err)
+			return nil, err
+		}
+		balance.UpdatedAt, err = parseTime(updatedAtBytes)
+		if err != nil {
+			log.Printf("Failed to parse updated_at: %v", err)
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+	return balances, rows.Err()
+}
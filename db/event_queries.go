@@ -0,0 +1,62 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+
+	"golang-order-matching-system/models"
+)
+
+// CreateOrderEventTx inserts an order event within a transaction
+func CreateOrderEventTx(event *models.OrderEvent, tx *sql.Tx) error {
+	query := `
+		INSERT INTO order_events (order_id, symbol, type, details, created_at)
+		VALUES (?, ?, ?, ?, ?)`
+	result, err := tx.Exec(query,
+		event.OrderID,
+		event.Symbol,
+		event.Type,
+		event.Details,
+		event.CreatedAt)
+	if err != nil {
+		log.Printf("Failed to create order event: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Printf("Failed to get last insert ID: %v", err)
+		return err
+	}
+	event.ID = id
+	return nil
+}
+
+// GetOrderEvents retrieves the events recorded for an order in chronological order
+func GetOrderEvents(orderID int64) ([]models.OrderEvent, error) {
+	var events []models.OrderEvent
+	query := `
+		SELECT id, order_id, symbol, type, details, created_at
+		FROM order_events WHERE order_id = ? ORDER BY id`
+	rows, err := DB.Query(query, orderID)
+	if err != nil {
+		log.Printf("Failed to get order events: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event models.OrderEvent
+		var createdAtBytes []byte
+		if err := rows.Scan(&event.ID, &event.OrderID, &event.Symbol, &event.Type, &event.Details, &createdAtBytes); err != nil {
+			log.Printf("Failed to scan order event: %v", err)
+			return nil, err
+		}
+		event.CreatedAt, err = parseTime(createdAtBytes)
+		if err != nil {
+			log.Printf("Failed to parse created_at: %v", err)
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
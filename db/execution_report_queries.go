@@ -0,0 +1,91 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+
+	"golang-order-matching-system/models"
+)
+
+// CreateExecutionReportTx inserts an execution report within a transaction, or
+// on its own when tx is nil, and sets its sequence number
+func CreateExecutionReportTx(report *models.ExecutionReport, tx *sql.Tx) error {
+	query := `
+		INSERT INTO execution_reports (account_id, order_id, symbol, side, order_type, exec_type, status, price,
+			quantity, filled_quantity, remaining_quantity, last_price, last_quantity, trade_id, reject_code, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	args := []interface{}{
+		report.AccountID,
+		report.OrderID,
+		report.Symbol,
+		report.Side,
+		report.OrderType,
+		report.ExecType,
+		report.Status,
+		report.Price,
+		report.Quantity,
+		report.FilledQuantity,
+		report.RemainingQuantity,
+		report.LastPrice,
+		report.LastQuantity,
+		report.TradeID,
+		report.RejectCode,
+		report.Reason,
+		report.CreatedAt,
+	}
+
+	var result sql.Result
+	var err error
+	if tx != nil {
+		result, err = tx.Exec(query, args...)
+	} else {
+		result, err = DB.Exec(query, args...)
+	}
+	if err != nil {
+		log.Printf("Failed to create execution report: %v", err)
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Printf("Failed to get last insert ID: %v", err)
+		return err
+	}
+	report.Seq = id
+	return nil
+}
+
+// GetExecutionReports retrieves up to limit execution reports of an account with
+// a sequence number above since, oldest first
+func GetExecutionReports(accountID, since int64, limit int) ([]models.ExecutionReport, error) {
+	var reports []models.ExecutionReport
+	query := `
+		SELECT id, account_id, order_id, symbol, side, order_type, exec_type, status, price, quantity,
+			filled_quantity, remaining_quantity, last_price, last_quantity, trade_id, reject_code, reason, created_at
+		FROM execution_reports WHERE account_id = ? AND id > ? ORDER BY id LIMIT ?`
+	rows, err := DB.Query(query, accountID, since, limit)
+	if err != nil {
+		log.Printf("Failed to get execution reports: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var report models.ExecutionReport
+		var createdAtBytes []byte
+		err := rows.Scan(&report.Seq, &report.AccountID, &report.OrderID, &report.Symbol, &report.Side,
+			&report.OrderType, &report.ExecType, &report.Status, &report.Price, &report.Quantity,
+			&report.FilledQuantity, &report.RemainingQuantity, &report.LastPrice, &report.LastQuantity,
+			&report.TradeID, &report.RejectCode, &report.Reason, &createdAtBytes)
+		if err != nil {
+			log.Printf("Failed to scan execution report: %v", err)
+			return nil, err
+		}
+		report.CreatedAt, err = parseTime(createdAtBytes)
+		if err != nil {
+			log.Printf("Failed to parse created_at: %v", err)
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"log"
+	"time"
+
+	"golang-order-matching-system/models"
+)
+
+// GetFeeTiers retrieves the fee schedules of all instruments, each ordered by minimum volume
+func GetFeeTiers() (map[string][]models.FeeTier, error) {
+	schedules := make(map[string][]models.FeeTier)
+	rows, err := DB.Query(`SELECT symbol, min_volume, maker_rate, taker_rate FROM fee_tiers ORDER BY symbol, min_volume`)
+	if err != nil {
+		log.Printf("Failed to get fee tiers: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var symbol string
+		var tier models.FeeTier
+		if err := rows.Scan(&symbol, &tier.MinVolume, &tier.MakerRate, &tier.TakerRate); err != nil {
+			log.Printf("Failed to scan fee tier: %v", err)
+			return nil, err
+		}
+		schedules[symbol] = append(schedules[symbol], tier)
+	}
+	return schedules, rows.Err()
+}
+
+// ReplaceFeeTiers replaces the fee schedule of an instrument
+func ReplaceFeeTiers(symbol string, tiers []models.FeeTier) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Failed to begin transaction: %v", err)
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM fee_tiers WHERE symbol = ?`, symbol); err != nil {
+		tx.Rollback()
+		log.Printf("Failed to delete fee tiers: %v", err)
+		return err
+	}
+	for _, tier := range tiers {
+		_, err := tx.Exec(`INSERT INTO fee_tiers (symbol, min_volume, maker_rate, taker_rate) VALUES (?, ?, ?, ?)`,
+			symbol, tier.MinVolume, tier.MakerRate, tier.TakerRate)
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Failed to create fee tier: %v", err)
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Failed to commit fee tiers: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetTradedVolumes returns the quote notional every account traded per symbol since the given time
+func GetTradedVolumes(since time.Time) ([]models.TradedVolume, error) {
+	var volumes []models.TradedVolume
+	query := `
+		SELECT account_id, symbol, CAST(SUM(price * quantity) AS DECIMAL(30,8)) FROM (
+			SELECT buy_account_id AS account_id, symbol, price, quantity FROM trades WHERE created_at >= ?
+			UNION ALL
+			SELECT sell_account_id AS account_id, symbol, price, quantity FROM trades WHERE created_at >= ?
+		) t
+		GROUP BY account_id, symbol`
+	rows, err := DB.Query(query, since, since)
+	if err != nil {
+		log.Printf("Failed to get traded volumes: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var volume models.TradedVolume
+		if err := rows.Scan(&volume.AccountID, &volume.Symbol, &volume.Volume); err != nil {
+			log.Printf("Failed to scan traded volume: %v", err)
+			return nil, err
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes, rows.Err()
+}
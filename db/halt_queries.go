@@ -0,0 +1,62 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+
+	"golang-order-matching-system/models"
+)
+
+// CreateHaltTx inserts a new trading halt within a transaction
+func CreateHaltTx(halt *models.Halt, tx *sql.Tx) error {
+	result, err := tx.Exec(`
+		INSERT INTO halts (symbol, reason, message, reference_price, trigger_price, halted_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		halt.Symbol, halt.Reason, halt.Message, halt.ReferencePrice, halt.TriggerPrice, halt.HaltedAt)
+	if err != nil {
+		log.Printf("Failed to create halt: %v", err)
+		return err
+	}
+	if halt.ID, err = result.LastInsertId(); err != nil {
+		log.Printf("Failed to get last insert ID: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ResumeHaltTx records the end of a trading halt and its reopening auction within a transaction
+func ResumeHaltTx(halt *models.Halt, tx *sql.Tx) error {
+	var price *models.Decimal
+	var volume models.Decimal
+	if halt.Reopening != nil {
+		price, volume = halt.Reopening.Price, halt.Reopening.Volume
+	}
+	_, err := tx.Exec(`UPDATE halts SET resumed_at = ?, reopen_price = ?, reopen_volume = ? WHERE id = ?`,
+		halt.ResumedAt, price, volume, halt.ID)
+	if err != nil {
+		log.Printf("Failed to resume halt: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetActiveHalt retrieves the halt a symbol is currently in, or nil if it is not halted
+func GetActiveHalt(symbol string) (*models.Halt, error) {
+	halt := &models.Halt{}
+	var haltedAtBytes []byte
+	err := DB.QueryRow(`
+		SELECT id, symbol, reason, message, reference_price, trigger_price, halted_at
+		FROM halts WHERE symbol = ? AND resumed_at IS NULL ORDER BY id DESC LIMIT 1`, symbol).Scan(
+		&halt.ID, &halt.Symbol, &halt.Reason, &halt.Message, &halt.ReferencePrice, &halt.TriggerPrice, &haltedAtBytes)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		log.Printf("Failed to get active halt: %v", err)
+		return nil, err
+	}
+	if halt.HaltedAt, err = parseTime(haltedAtBytes); err != nil {
+		log.Printf("Failed to parse halted_at: %v", err)
+		return nil, err
+	}
+	return halt, nil
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+
+	"golang-order-matching-system/models"
+)
+
+// instrumentColumns lists the columns read by scanInstrument, in scan order
+const instrumentColumns = `symbol, base_asset, quote_asset, tick_size, lot_size, min_quantity, max_quantity,
+		min_notional, max_notional, price_precision, market_collar_pct, circuit_breaker_pct, circuit_breaker_window,
+		matching_algorithm, top_order_max, lmm_account_id, lmm_pct, status, created_at, updated_at`
+
+// CreateInstrument inserts a new instrument
+func CreateInstrument(inst *models.Instrument) error {
+	query := `
+		INSERT INTO instruments (symbol, base_asset, quote_asset, tick_size, lot_size, min_quantity, max_quantity,
+			min_notional, max_notional, price_precision, market_collar_pct, circuit_breaker_pct, circuit_breaker_window,
+			matching_algorithm, top_order_max, lmm_account_id, lmm_pct, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := DB.Exec(query,
+		inst.Symbol,
+		inst.BaseAsset,
+		inst.QuoteAsset,
+		inst.TickSize,
+		inst.LotSize,
+		inst.MinQuantity,
+		inst.MaxQuantity,
+		inst.MinNotional,
+		inst.MaxNotional,
+		inst.PricePrecision,
+		inst.MarketCollarPct,
+		inst.CircuitBreakerPct,
+		inst.CircuitBreakerWindow,
+		inst.MatchingAlgorithm,
+		inst.TopOrderMax,
+		inst.LMMAccountID,
+		inst.LMMPct,
+		inst.Status,
+		inst.CreatedAt,
+		inst.UpdatedAt)
+	if err != nil {
+		log.Printf("Failed to create instrument: %v", err)
+		return err
+	}
+	return nil
+}
+
+// UpdateInstrument updates the trading rules of an existing instrument
+func UpdateInstrument(inst *models.Instrument) error {
+	query := `
+		UPDATE instruments
+		SET base_asset = ?, quote_asset = ?, tick_size = ?, lot_size = ?, min_quantity = ?, max_quantity = ?,
+			min_notional = ?, max_notional = ?, price_precision = ?, market_collar_pct = ?, circuit_breaker_pct = ?,
+			circuit_breaker_window = ?, matching_algorithm = ?, top_order_max = ?, lmm_account_id = ?, lmm_pct = ?,
+			status = ?, updated_at = ?
+		WHERE symbol = ?`
+	result, err := DB.Exec(query,
+		inst.BaseAsset,
+		inst.QuoteAsset,
+		inst.TickSize,
+		inst.LotSize,
+		inst.MinQuantity,
+		inst.MaxQuantity,
+		inst.MinNotional,
+		inst.MaxNotional,
+		inst.PricePrecision,
+		inst.MarketCollarPct,
+		inst.CircuitBreakerPct,
+		inst.CircuitBreakerWindow,
+		inst.MatchingAlgorithm,
+		inst.TopOrderMax,
+		inst.LMMAccountID,
+		inst.LMMPct,
+		inst.Status,
+		inst.UpdatedAt,
+		inst.Symbol)
+	if err != nil {
+		log.Printf("Failed to update instrument: %v", err)
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteInstrument removes an instrument
+func DeleteInstrument(symbol string) error {
+	result, err := DB.Exec(`DELETE FROM instruments WHERE symbol = ?`, symbol)
+	if err != nil {
+		log.Printf("Failed to delete instrument: %v", err)
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetInstruments retrieves all instruments
+func GetInstruments() ([]models.Instrument, error) {
+	var instruments []models.Instrument
+	query := `
+		SELECT ` + instrumentColumns + `
+		FROM instruments ORDER BY symbol`
+	rows, err := DB.Query(query)
+	if err != nil {
+		log.Printf("Failed to get instruments: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		inst, err := scanInstrument(rows)
+		if err != nil {
+			log.Printf("Failed to scan instrument: %v", err)
+			return nil, err
+		}
+		instruments = append(instruments, *inst)
+	}
+	return instruments, rows.Err()
+}
+
+// scanInstrument reads one instrument selected with instrumentColumns
+func scanInstrument(row rowScanner) (*models.Instrument, error) {
+	inst := &models.Instrument{}
+	var createdAtBytes, updatedAtBytes []byte
+	err := row.Scan(
+		&inst.Symbol,
+		&inst.BaseAsset,
+		&inst.QuoteAsset,
+		&inst.TickSize,
+		&inst.LotSize,
+		&inst.MinQuantity,
+		&inst.MaxQuantity,
+		&inst.MinNotional,
+		&inst.MaxNotional,
+		&inst.PricePrecision,
+		&inst.MarketCollarPct,
+		&inst.CircuitBreakerPct,
+		&inst.CircuitBreakerWindow,
+		&inst.MatchingAlgorithm,
+		&inst.TopOrderMax,
+		&inst.LMMAccountID,
+		&inst.LMMPct,
+		&inst.Status,
+		&createdAtBytes,
+		&updatedAtBytes)
+	if err != nil {
+		return nil, err
+	}
+	inst.CreatedAt, err = parseTime(createdAtBytes)
+	if err != nil {
+		return nil, err
+	}
+	inst.UpdatedAt, err = parseTime(updatedAtBytes)
+	if err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
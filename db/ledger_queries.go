@@ -0,0 +1,264 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"golang-order-matching-system/models"
+)
+
+// ErrUnbalancedJournal is returned when the entries of a journal do not sum to zero for an asset
+var ErrUnbalancedJournal = errors.New("journal does not balance")
+
+// PostJournalTx checks that a journal balances for every asset, stores it with
+// its entries and applies the entries to the account balances within a
+// transaction. With a nil tx the journal is posted in its own transaction.
+func PostJournalTx(journal *models.Journal, tx *sql.Tx) (err error) {
+	if tx == nil {
+		if tx, err = DB.Begin(); err != nil {
+			log.Printf("Failed to begin transaction: %v", err)
+			return err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			if err = tx.Commit(); err != nil {
+				log.Printf("Failed to commit journal: %v", err)
+			}
+		}()
+	}
+
+	entries := journal.Entries[:0]
+	sums := make(map[string]models.Decimal)
+	for _, entry := range journal.Entries {
+		if entry.Amount.IsZero() {
+			continue
+		}
+		sums[entry.Asset] = sums[entry.Asset].Add(entry.Amount)
+		entries = append(entries, entry)
+	}
+	journal.Entries = entries
+	for asset, sum := range sums {
+		if !sum.IsZero() {
+			return fmt.Errorf("%w: %s entries of %s journal %d sum to %s", ErrUnbalancedJournal, asset, journal.Type, journal.ReferenceID, sum)
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if err := applyEntriesTx(entries, tx); err != nil {
+		return err
+	}
+
+	if journal.CreatedAt.IsZero() {
+		journal.CreatedAt = time.Now()
+	}
+	result, err := tx.Exec(`INSERT INTO journals (type, reference_id, created_at) VALUES (?, ?, ?)`,
+		journal.Type, journal.ReferenceID, journal.CreatedAt)
+	if err != nil {
+		log.Printf("Failed to create journal: %v", err)
+		return err
+	}
+	if journal.ID, err = result.LastInsertId(); err != nil {
+		log.Printf("Failed to get last insert ID: %v", err)
+		return err
+	}
+
+	placeholders := make([]string, len(entries))
+	args := make([]interface{}, 0, 6*len(entries))
+	for i := range entries {
+		entry := &entries[i]
+		entry.JournalID = journal.ID
+		entry.Type = journal.Type
+		entry.ReferenceID = journal.ReferenceID
+		entry.CreatedAt = journal.CreatedAt
+		placeholders[i] = "(?, ?, ?, ?, ?, ?)"
+		args = append(args, entry.JournalID, entry.AccountID, entry.Asset, entry.Bucket, entry.Amount, entry.CreatedAt)
+	}
+	query := `INSERT INTO ledger_entries (journal_id, account_id, asset, bucket, amount, created_at) VALUES ` +
+		strings.Join(placeholders, ", ")
+	if _, err := tx.Exec(query, args...); err != nil {
+		log.Printf("Failed to create ledger entries: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyEntriesTx adds ledger entries to the balances they move, one adjustment per
+// account and asset in a fixed order so concurrent postings lock rows consistently
+func applyEntriesTx(entries []models.LedgerEntry, tx *sql.Tx) error {
+	type balanceKey struct {
+		accountID int64
+		asset     string
+	}
+	type delta struct {
+		available, held models.Decimal
+	}
+	deltas := make(map[balanceKey]*delta)
+	var keys []balanceKey
+	for _, entry := range entries {
+		if entry.AccountID == models.ExternalAccountID || entry.AccountID == models.FeeAccountID {
+			continue
+		}
+		key := balanceKey{entry.AccountID, entry.Asset}
+		d, ok := deltas[key]
+		if !ok {
+			d = &delta{}
+			deltas[key] = d
+			keys = append(keys, key)
+		}
+		if entry.Bucket == models.BucketHeld {
+			d.held = d.held.Add(entry.Amount)
+		} else {
+			d.available = d.available.Add(entry.Amount)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].accountID != keys[j].accountID {
+			return keys[i].accountID < keys[j].accountID
+		}
+		return keys[i].asset < keys[j].asset
+	})
+	for _, key := range keys {
+		d := deltas[key]
+		if err := adjustBalanceTx(key.accountID, key.asset, d.available, d.held, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetLedgerEntries retrieves the ledger entries of an account, newest first,
+// optionally filtered by asset. Entries with an ID of before or above are skipped
+// when before is positive, which allows paging.
+func GetLedgerEntries(accountID int64, asset string, before int64, limit int) ([]models.LedgerEntry, error) {
+	var entries []models.LedgerEntry
+	query := `
+		SELECT l.id, l.journal_id, j.type, j.reference_id, l.account_id, l.asset, l.bucket, l.amount, l.created_at
+		FROM ledger_entries l JOIN journals j ON j.id = l.journal_id
+		WHERE l.account_id = ?`
+	args := []interface{}{accountID}
+	if asset != "" {
+		query += ` AND l.asset = ?`
+		args = append(args, asset)
+	}
+	if before > 0 {
+		query += ` AND l.id < ?`
+		args = append(args, before)
+	}
+	query += ` ORDER BY l.id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Failed to get ledger entries: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.LedgerEntry
+		var createdAtBytes []byte
+		err := rows.Scan(&entry.ID, &entry.JournalID, &entry.Type, &entry.ReferenceID, &entry.AccountID,
+			&entry.Asset, &entry.Bucket, &entry.Amount, &createdAtBytes)
+		if err != nil {
+			log.Printf("Failed to scan ledger entry: %v", err)
+			return nil, err
+		}
+		entry.CreatedAt, err = parseTime(createdAtBytes)
+		if err != nil {
+			log.Printf("Failed to parse created_at: %v", err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// CheckLedger verifies that the ledger entries sum to zero for every asset and
+// within every journal, and that every balance equals the sum of its entries
+func CheckLedger() (*models.LedgerCheck, error) {
+	check := &models.LedgerCheck{Imbalances: []models.LedgerImbalance{}, CheckedAt: time.Now()}
+	if err := DB.QueryRow(`SELECT COUNT(*) FROM journals`).Scan(&check.Journals); err != nil {
+		log.Printf("Failed to count journals: %v", err)
+		return nil, err
+	}
+	if err := DB.QueryRow(`SELECT COUNT(*) FROM ledger_entries`).Scan(&check.Entries); err != nil {
+		log.Printf("Failed to count ledger entries: %v", err)
+		return nil, err
+	}
+
+	// Every asset and every journal must sum to zero
+	queries := []string{
+		`SELECT asset, 0, SUM(amount) FROM ledger_entries GROUP BY asset HAVING SUM(amount) <> 0`,
+		`SELECT asset, journal_id, SUM(amount) FROM ledger_entries GROUP BY journal_id, asset HAVING SUM(amount) <> 0`,
+	}
+	for _, query := range queries {
+		rows, err := DB.Query(query)
+		if err != nil {
+			log.Printf("Failed to check ledger sums: %v", err)
+			return nil, err
+		}
+		for rows.Next() {
+			var imbalance models.LedgerImbalance
+			if err := rows.Scan(&imbalance.Asset, &imbalance.JournalID, &imbalance.Actual); err != nil {
+				rows.Close()
+				log.Printf("Failed to scan ledger sum: %v", err)
+				return nil, err
+			}
+			check.Imbalances = append(check.Imbalances, imbalance)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Every balance must equal the entries posted to it
+	rows, err := DB.Query(`
+		SELECT b.account_id, b.asset, b.available, b.held,
+			COALESCE(SUM(CASE WHEN l.bucket = 'available' THEN l.amount END), 0),
+			COALESCE(SUM(CASE WHEN l.bucket = 'held' THEN l.amount END), 0)
+		FROM balances b
+		LEFT JOIN ledger_entries l ON l.account_id = b.account_id AND l.asset = b.asset
+		GROUP BY b.account_id, b.asset, b.available, b.held`)
+	if err != nil {
+		log.Printf("Failed to check balances against ledger: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var accountID int64
+		var asset string
+		var available, held, ledgerAvailable, ledgerHeld models.Decimal
+		if err := rows.Scan(&accountID, &asset, &available, &held, &ledgerAvailable, &ledgerHeld); err != nil {
+			log.Printf("Failed to scan balance check: %v", err)
+			return nil, err
+		}
+		if !available.Equal(ledgerAvailable) {
+			check.Imbalances = append(check.Imbalances, models.LedgerImbalance{
+				Asset: asset, AccountID: accountID, Bucket: models.BucketAvailable, Expected: ledgerAvailable, Actual: available,
+			})
+		}
+		if !held.Equal(ledgerHeld) {
+			check.Imbalances = append(check.Imbalances, models.LedgerImbalance{
+				Asset: asset, AccountID: accountID, Bucket: models.BucketHeld, Expected: ledgerHeld, Actual: held,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	check.Balanced = len(check.Imbalances) == 0
+	return check, nil
+}
@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"time"
 	"golang-order-matching-system/models"
 )
 
@@ -12,18 +11,29 @@ import (
 // CreateOrderTx inserts a new order within a transaction
 func CreateOrderTx(order *models.Order, tx *sql.Tx) error {
 	query := `
-		INSERT INTO orders (symbol, side, type, price, quantity, remaining_quantity, status, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		INSERT INTO orders (account_id, symbol, side, type, time_in_force, price, stop_price, protection_price, quantity,
+			remaining_quantity, display_quantity, visible_quantity, post_only, hold, stp_mode, status, created_at, updated_at, queued_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	result, err := tx.Exec(query,
+		order.AccountID,
 		order.Symbol,
 		order.Side,
 		order.Type,
+		order.TimeInForce,
 		order.Price,
+		order.StopPrice,
+		order.ProtectionPrice,
 		order.Quantity,
 		order.Quantity, // Initial remaining_quantity equals quantity
+		order.DisplayQuantity,
+		order.VisibleQuantity,
+		order.PostOnly,
+		order.Hold,
+		order.STPMode,
 		order.Status,
 		order.CreatedAt,
-		order.UpdatedAt)
+		order.UpdatedAt,
+		order.QueuedAt)
 	if err != nil {
 		log.Printf("Failed to create order: %v", err)
 		return err
@@ -41,7 +51,7 @@ func CreateOrderTx(order *models.Order, tx *sql.Tx) error {
 func UpdateOrderTx(order *models.Order, tx *sql.Tx) error {
 	query := `
 		UPDATE orders 
-		SET remaining_quantity = ?, status = ?, updated_at = ?
+		SET type = ?, price = ?, protection_price = ?, quantity = ?, remaining_quantity = ?, visible_quantity = ?, hold = ?, status = ?, updated_at = ?, queued_at = ?
 		WHERE id = ?`
 
 	// Add fallback for non-transactional usage with nil check for DB
@@ -51,9 +61,16 @@ func UpdateOrderTx(order *models.Order, tx *sql.Tx) error {
 			return fmt.Errorf("database connection is nil")
 		}
 		_, err := DB.Exec(query,
+			order.Type,
+			order.Price,
+			order.ProtectionPrice,
+			order.Quantity,
 			order.RemainingQuantity,
+			order.VisibleQuantity,
+			order.Hold,
 			order.Status,
 			order.UpdatedAt,
+			order.QueuedAt,
 			order.ID)
 		if err != nil {
 			log.Printf("Failed to update order (non-tx): %v", err)
@@ -64,9 +81,16 @@ func UpdateOrderTx(order *models.Order, tx *sql.Tx) error {
 
 	// If tx is provided, use it
 	_, err := tx.Exec(query,
+		order.Type,
+		order.Price,
+		order.ProtectionPrice,
+		order.Quantity,
 		order.RemainingQuantity,
+		order.VisibleQuantity,
+		order.Hold,
 		order.Status,
 		order.UpdatedAt,
+		order.QueuedAt,
 		order.ID)
 	if err != nil {
 		log.Printf("Failed to update order (tx): %v", err)
@@ -77,144 +101,134 @@ func UpdateOrderTx(order *models.Order, tx *sql.Tx) error {
 
 // GetOrderByID retrieves an order by its ID
 func GetOrderByID(orderID int64) (*models.Order, error) {
-	order := &models.Order{}
 	query := `
-		SELECT id, symbol, side, type, price, quantity, remaining_quantity, status, created_at, updated_at
+		SELECT ` + orderColumns + `
 		FROM orders WHERE id = ?`
-	var createdAtBytes, updatedAtBytes []byte
-	err := DB.QueryRow(query, orderID).Scan(
-		&order.ID,
-		&order.Symbol,
-		&order.Side,
-		&order.Type,
-		&order.Price,
-		&order.Quantity,
-		&order.RemainingQuantity,
-		&order.Status,
-		&createdAtBytes,
-		&updatedAtBytes)
+	order, err := scanOrder(DB.QueryRow(query, orderID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	} else if err != nil {
 		log.Printf("Failed to get order: %v", err)
 		return nil, err
 	}
-	order.CreatedAt, err = parseTime(createdAtBytes)
-	if err != nil {
-		log.Printf("Failed to parse created_at: %v", err)
-		return nil, err
-	}
-	order.UpdatedAt, err = parseTime(updatedAtBytes)
-	if err != nil {
-		log.Printf("Failed to parse updated_at: %v", err)
-		return nil, err
-	}
 	return order, nil
 }
 
-// UpdateOrderStatus updates the status of an existing order with transition validation
-func UpdateOrderStatus(orderID int64, status string, remainingQuantity int) error {
-	order, err := GetOrderByID(orderID)
-	if err != nil {
-		return err
-	}
-	if order == nil {
-		return sql.ErrNoRows
-	}
-
-	validStatuses := map[string]bool{"open": true, "partially_filled": true, "filled": true, "canceled": true}
-	if !validStatuses[status] {
-		return fmt.Errorf("invalid status: %s", status)
-	}
-
-	if remainingQuantity < 0 || remainingQuantity > order.Quantity {
-		return fmt.Errorf("invalid remaining_quantity: %d", remainingQuantity)
-	}
-
-	validTransitions := map[string]map[string]bool{
-		"open":           {"partially_filled": true, "filled": true, "canceled": true},
-		"partially_filled": {"filled": true, "canceled": true},
-		"filled":          {},
-		"canceled":        {},
+// GetOpenOrders retrieves all resting orders in arrival order, optionally filtered by symbol
+func GetOpenOrders(symbol string) ([]models.Order, error) {
+	var orders []models.Order
+	query := `
+		SELECT ` + orderColumns + `
+		FROM orders
+		WHERE status IN ('open', 'partially_filled')`
+	args := []interface{}{}
+	if symbol != "" {
+		query += ` AND symbol = ?`
+		args = append(args, symbol)
 	}
+	query += ` ORDER BY queued_at ASC, id ASC`
 
-	if !validTransitions[order.Status][status] {
-		return fmt.Errorf("invalid transition from %s to %s", order.Status, status)
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Failed to get open orders: %v", err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	switch status {
-	case "filled":
-		if remainingQuantity != 0 {
-			return fmt.Errorf("filled requires remaining_quantity 0")
-		}
-	case "open", "partially_filled":
-		if remainingQuantity == 0 {
-			return fmt.Errorf("%s requires remaining_quantity > 0", status)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			log.Printf("Failed to scan order: %v", err)
+			return nil, err
 		}
+		orders = append(orders, *order)
 	}
-
-	order.Status = status
-	order.RemainingQuantity = remainingQuantity
-	order.UpdatedAt = time.Now()
-	return UpdateOrderTx(order, nil) // Use nil tx for non-transactional update
-}
-
-// CancelOrder updates the order status to "canceled"
-func CancelOrder(orderID int64) error {
-    order, err := GetOrderByID(orderID)
-    if err != nil {
-        return err
-    }
-    if order == nil {
-        return sql.ErrNoRows
-    }
-
-    // Check if the order is not cancellable
-    if order.Status == "filled" || order.Status == "canceled" {
-        return fmt.Errorf("order %d cannot be canceled, status is %s", orderID, order.Status)
-    }
-
-    order.Status = "canceled"
-    order.UpdatedAt = time.Now()
-    return UpdateOrderTx(order, nil)
+	return orders, rows.Err()
 }
 
-// GetOrderBook retrieves the current order book for a symbol, optionally with full list
-func GetOrderBook(symbol string, full bool) ([]models.Order, error) {
+// GetOrdersByAccount retrieves the orders of an account, newest first, optionally filtered by status
+func GetOrdersByAccount(accountID int64, status string) ([]models.Order, error) {
 	var orders []models.Order
 	query := `
-		SELECT id, symbol, side, type, price, quantity, remaining_quantity, status, created_at, updated_at
-		FROM orders 
-		WHERE symbol = ? AND status IN ('open', 'partially_filled')`
-	if !full {
-		query += ` ORDER BY price DESC, created_at ASC LIMIT 10`
+		SELECT ` + orderColumns + `
+		FROM orders
+		WHERE account_id = ?`
+	args := []interface{}{accountID}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
 	}
-	rows, err := DB.Query(query, symbol)
+	query += ` ORDER BY id DESC`
+
+	rows, err := DB.Query(query, args...)
 	if err != nil {
-		log.Printf("Failed to get order book: %v", err)
+		log.Printf("Failed to get account orders: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var order models.Order
-		var price *float64
-		var createdAtBytes, updatedAtBytes []byte
-		if err := rows.Scan(&order.ID, &order.Symbol, &order.Side, &order.Type, &price,
-			&order.Quantity, &order.RemainingQuantity, &order.Status, &createdAtBytes, &updatedAtBytes); err != nil {
-			log.Printf("Failed to scan order: %v", err)
-			return nil, err
-		}
-		order.Price = price
-		order.CreatedAt, err = parseTime(createdAtBytes)
-		if err != nil {
-			return nil, err
-		}
-		order.UpdatedAt, err = parseTime(updatedAtBytes)
+		order, err := scanOrder(rows)
 		if err != nil {
+			log.Printf("Failed to scan order: %v", err)
 			return nil, err
 		}
-		orders = append(orders, order)
+		orders = append(orders, *order)
 	}
-	return orders, nil
+	return orders, rows.Err()
+}
+
+// orderColumns lists the columns read by scanOrder, in scan order
+const orderColumns = `id, account_id, symbol, side, type, time_in_force, price, stop_price, protection_price, quantity, remaining_quantity,
+		display_quantity, visible_quantity, post_only, hold, stp_mode, status, created_at, updated_at, queued_at`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanOrder reads one order selected with orderColumns
+func scanOrder(row rowScanner) (*models.Order, error) {
+	order := &models.Order{}
+	var createdAtBytes, updatedAtBytes, queuedAtBytes []byte
+	err := row.Scan(
+		&order.ID,
+		&order.AccountID,
+		&order.Symbol,
+		&order.Side,
+		&order.Type,
+		&order.TimeInForce,
+		&order.Price,
+		&order.StopPrice,
+		&order.ProtectionPrice,
+		&order.Quantity,
+		&order.RemainingQuantity,
+		&order.DisplayQuantity,
+		&order.VisibleQuantity,
+		&order.PostOnly,
+		&order.Hold,
+		&order.STPMode,
+		&order.Status,
+		&createdAtBytes,
+		&updatedAtBytes,
+		&queuedAtBytes)
+	if err != nil {
+		return nil, err
+	}
+	order.CreatedAt, err = parseTime(createdAtBytes)
+	if err != nil {
+		log.Printf("Failed to parse created_at: %v", err)
+		return nil, err
+	}
+	order.UpdatedAt, err = parseTime(updatedAtBytes)
+	if err != nil {
+		log.Printf("Failed to parse updated_at: %v", err)
+		return nil, err
+	}
+	order.QueuedAt, err = parseTime(queuedAtBytes)
+	if err != nil {
+		log.Printf("Failed to parse queued_at: %v", err)
+		return nil, err
+	}
+	return order, nil
 }
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+
+	"golang-order-matching-system/models"
+)
+
+// GetRiskLimits retrieves all configured risk limits
+func GetRiskLimits() ([]models.RiskLimits, error) {
+	var limits []models.RiskLimits
+	rows, err := DB.Query(`
+		SELECT account_id, symbol, max_order_quantity, max_notional, max_open_orders, price_band_pct, updated_at
+		FROM risk_limits ORDER BY account_id, symbol`)
+	if err != nil {
+		log.Printf("Failed to get risk limits: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l models.RiskLimits
+		var updatedAtBytes []byte
+		if err := rows.Scan(&l.AccountID, &l.Symbol, &l.MaxOrderQuantity, &l.MaxNotional, &l.MaxOpenOrders,
+			&l.PriceBandPct, &updatedAtBytes); err != nil {
+			log.Printf("Failed to scan risk limits: %v", err)
+			return nil, err
+		}
+		if l.UpdatedAt, err = parseTime(updatedAtBytes); err != nil {
+			log.Printf("Failed to parse updated_at: %v", err)
+			return nil, err
+		}
+		limits = append(limits, l)
+	}
+	return limits, rows.Err()
+}
+
+// SaveRiskLimits creates or replaces the risk limits of an account and symbol scope
+func SaveRiskLimits(l *models.RiskLimits) error {
+	_, err := DB.Exec(`
+		INSERT INTO risk_limits (account_id, symbol, max_order_quantity, max_notional, max_open_orders, price_band_pct, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE max_order_quantity = VALUES(max_order_quantity), max_notional = VALUES(max_notional),
+			max_open_orders = VALUES(max_open_orders), price_band_pct = VALUES(price_band_pct), updated_at = VALUES(updated_at)`,
+		l.AccountID, l.Symbol, l.MaxOrderQuantity, l.MaxNotional, l.MaxOpenOrders, l.PriceBandPct, l.UpdatedAt)
+	if err != nil {
+		log.Printf("Failed to save risk limits: %v", err)
+		return err
+	}
+	return nil
+}
+
+// DeleteRiskLimits removes the risk limits of an account and symbol scope
+func DeleteRiskLimits(accountID int64, symbol string) error {
+	result, err := DB.Exec(`DELETE FROM risk_limits WHERE account_id = ? AND symbol = ?`, accountID, symbol)
+	if err != nil {
+		log.Printf("Failed to delete risk limits: %v", err)
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
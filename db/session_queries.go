@@ -0,0 +1,85 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"golang-order-matching-system/models"
+)
+
+// GetTradingPhase retrieves the stored phase of a symbol, continuous if none was stored
+func GetTradingPhase(symbol string) (string, error) {
+	var phase string
+	err := DB.QueryRow(`SELECT phase FROM trading_phases WHERE symbol = ?`, symbol).Scan(&phase)
+	if err == sql.ErrNoRows {
+		return models.PhaseContinuous, nil
+	} else if err != nil {
+		log.Printf("Failed to get trading phase: %v", err)
+		return "", err
+	}
+	return phase, nil
+}
+
+// SaveTradingPhaseTx stores the phase of a symbol within a transaction
+func SaveTradingPhaseTx(symbol, phase string, tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		INSERT INTO trading_phases (symbol, phase, updated_at) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE phase = VALUES(phase), updated_at = VALUES(updated_at)`,
+		symbol, phase, time.Now())
+	if err != nil {
+		log.Printf("Failed to save trading phase: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetSessionSchedules retrieves the session schedules of all instruments, each ordered by start time
+func GetSessionSchedules() (map[string][]models.SessionPhase, error) {
+	schedules := make(map[string][]models.SessionPhase)
+	rows, err := DB.Query(`SELECT symbol, start_time, phase FROM session_schedules ORDER BY symbol, start_time`)
+	if err != nil {
+		log.Printf("Failed to get session schedules: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var symbol string
+		var entry models.SessionPhase
+		if err := rows.Scan(&symbol, &entry.Start, &entry.Phase); err != nil {
+			log.Printf("Failed to scan session schedule: %v", err)
+			return nil, err
+		}
+		schedules[symbol] = append(schedules[symbol], entry)
+	}
+	return schedules, rows.Err()
+}
+
+// ReplaceSessionSchedule replaces the session schedule of an instrument
+func ReplaceSessionSchedule(symbol string, schedule []models.SessionPhase) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		log.Printf("Failed to begin transaction: %v", err)
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM session_schedules WHERE symbol = ?`, symbol); err != nil {
+		tx.Rollback()
+		log.Printf("Failed to delete session schedule: %v", err)
+		return err
+	}
+	for _, entry := range schedule {
+		_, err := tx.Exec(`INSERT INTO session_schedules (symbol, start_time, phase) VALUES (?, ?, ?)`,
+			symbol, entry.Start, entry.Phase)
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Failed to create session schedule entry: %v", err)
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Failed to commit session schedule: %v", err)
+		return err
+	}
+	return nil
+}
@@ -1,34 +1,57 @@
 package db
 
 import (
+	"database/sql"
 	"log"
 	"golang-order-matching-system/models"
 )
 
-// CreateTrade inserts a new trade into the database
-func CreateTrade(trade *models.Trade) error {
+// CreateTradeTx inserts a new trade within a transaction
+func CreateTradeTx(trade *models.Trade, tx *sql.Tx) error {
 	query := `
-		INSERT INTO trades (symbol, buy_order_id, sell_order_id, price, quantity, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)`
-	_, err := DB.Exec(query,
+		INSERT INTO trades (symbol, buy_order_id, sell_order_id, buy_account_id, sell_account_id, price, quantity,
+			taker_side, buy_fee, buy_fee_asset, sell_fee, sell_fee_asset, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	args := []interface{}{
 		trade.Symbol,
 		trade.BuyOrderID,
 		trade.SellOrderID,
+		trade.BuyAccountID,
+		trade.SellAccountID,
 		trade.Price,
 		trade.Quantity,
-		trade.CreatedAt)
+		trade.TakerSide,
+		trade.BuyFee,
+		trade.BuyFeeAsset,
+		trade.SellFee,
+		trade.SellFeeAsset,
+		trade.CreatedAt,
+	}
+
+	var result sql.Result
+	var err error
+	if tx == nil {
+		result, err = DB.Exec(query, args...)
+	} else {
+		result, err = tx.Exec(query, args...)
+	}
 	if err != nil {
 		log.Printf("Failed to create trade: %v", err)
 		return err
 	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Printf("Failed to get last insert ID: %v", err)
+		return err
+	}
+	trade.ID = int(id)
 	return nil
 }
 
 // GetTrades retrieves trades, optionally filtered by symbol
 func GetTrades(symbol string) ([]models.Trade, error) {
-	var trades []models.Trade
 	query := `
-		SELECT id, symbol, buy_order_id, sell_order_id, price, quantity, created_at
+		SELECT ` + tradeColumns + `
 		FROM trades`
 	args := []interface{}{}
 	if symbol != "" {
@@ -42,11 +65,35 @@ func GetTrades(symbol string) ([]models.Trade, error) {
 		return nil, err
 	}
 	defer rows.Close()
+	return scanTrades(rows)
+}
+
+// GetTradesAfter retrieves up to limit trades of a symbol with an ID above afterID, oldest first
+func GetTradesAfter(symbol string, afterID int64, limit int) ([]models.Trade, error) {
+	query := `
+		SELECT ` + tradeColumns + `
+		FROM trades WHERE symbol = ? AND id > ? ORDER BY id LIMIT ?`
+	rows, err := DB.Query(query, symbol, afterID, limit)
+	if err != nil {
+		log.Printf("Failed to get trades after %d: %v", afterID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	return scanTrades(rows)
+}
+
+// tradeColumns lists the columns read by scanTrades, in scan order
+const tradeColumns = `id, symbol, buy_order_id, sell_order_id, buy_account_id, sell_account_id, price, quantity,
+			taker_side, buy_fee, buy_fee_asset, sell_fee, sell_fee_asset, created_at`
 
+// scanTrades reads the trades selected with tradeColumns
+func scanTrades(rows *sql.Rows) ([]models.Trade, error) {
+	var trades []models.Trade
 	for rows.Next() {
 		var trade models.Trade
 		var createdAtBytes []byte
-		err := rows.Scan(&trade.ID, &trade.Symbol, &trade.BuyOrderID, &trade.SellOrderID, &trade.Price, &trade.Quantity, &createdAtBytes)
+		err := rows.Scan(&trade.ID, &trade.Symbol, &trade.BuyOrderID, &trade.SellOrderID, &trade.BuyAccountID, &trade.SellAccountID, &trade.Price, &trade.Quantity,
+			&trade.TakerSide, &trade.BuyFee, &trade.BuyFeeAsset, &trade.SellFee, &trade.SellFeeAsset, &createdAtBytes)
 		if err != nil {
 			log.Printf("Failed to scan trade: %v", err)
 			return nil, err
@@ -59,4 +106,17 @@ func GetTrades(symbol string) ([]models.Trade, error) {
 		trades = append(trades, trade)
 	}
 	return trades, nil
-}
\ No newline at end of file
+}
+
+// GetLastTradePrice returns the price of the most recent trade for a symbol, or nil if there is none
+func GetLastTradePrice(symbol string) (*models.Decimal, error) {
+	var price models.Decimal
+	err := DB.QueryRow(`SELECT price FROM trades WHERE symbol = ? ORDER BY id DESC LIMIT 1`, symbol).Scan(&price)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		log.Printf("Failed to get last trade price: %v", err)
+		return nil, err
+	}
+	return &price, nil
+}
@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"golang-order-matching-system/models"
+)
+
+// allocationLot rounds the allocations of symbols without a registered instrument
+var allocationLot = models.NewDecimal(1, 8)
+
+// LevelAllocation is the input of a matching algorithm: the quantity an incoming
+// order takes from one price level and the orders resting there
+type LevelAllocation struct {
+	Orders   []*models.Order // resting orders in time priority
+	Quantity models.Decimal  // at most the total visible quantity of Orders
+	Lot      models.Decimal  // proportional shares are rounded down to whole lots
+	Top      *models.Order   // the top order of the level, nil if it has none
+}
+
+// MatchingAlgorithm divides a price level among its resting orders. Allocate
+// returns the quantity each order trades, in the order of a.Orders; the
+// quantities add up to a.Quantity and none exceeds the visible quantity of its order.
+type MatchingAlgorithm interface {
+	Allocate(a LevelAllocation) []models.Decimal
+}
+
+// matchingAlgorithms builds the matching algorithm of an instrument by name
+var matchingAlgorithms = map[string]func(inst models.Instrument) MatchingAlgorithm{
+	models.MatchingFIFO:    func(models.Instrument) MatchingAlgorithm { return FIFO{} },
+	models.MatchingProRata: func(models.Instrument) MatchingAlgorithm { return ProRata{} },
+	models.MatchingTopFIFO: func(inst models.Instrument) MatchingAlgorithm { return TopFIFO{Max: inst.TopOrderMax} },
+	models.MatchingLMM: func(inst models.Instrument) MatchingAlgorithm {
+		return LMM{AccountID: inst.LMMAccountID, Pct: inst.LMMPct}
+	},
+}
+
+// algorithmFor returns the matching algorithm of an instrument, FIFO if it names none
+func algorithmFor(inst models.Instrument) MatchingAlgorithm {
+	if build, ok := matchingAlgorithms[inst.MatchingAlgorithm]; ok {
+		return build(inst)
+	}
+	return FIFO{}
+}
+
+// FIFO allocates in time priority: each order is filled up to its visible
+// quantity before the next one trades
+type FIFO struct{}
+
+// Allocate implements MatchingAlgorithm
+func (FIFO) Allocate(a LevelAllocation) []models.Decimal {
+	shares := make([]models.Decimal, len(a.Orders))
+	fillInOrder(a.Orders, shares, a.Quantity, nil)
+	return shares
+}
+
+// ProRata allocates in proportion to visible quantity. Each order gets
+// quantity × its visible lots / all visible lots, rounded down to whole lots; the
+// residual then goes to the orders in time priority, each up to its visible quantity.
+type ProRata struct{}
+
+// Allocate implements MatchingAlgorithm
+func (ProRata) Allocate(a LevelAllocation) []models.Decimal {
+	shares := make([]models.Decimal, len(a.Orders))
+	rest := proRata(a.Orders, shares, a.Quantity, a.Lot)
+	fillInOrder(a.Orders, shares, rest, nil)
+	return shares
+}
+
+// TopFIFO gives the top order of the level, the order that set a new best price
+// on its side and has not lost it since, priority up to Max (zero means no
+// limit). The rest is allocated in time priority with the remainder of the top
+// order last.
+type TopFIFO struct {
+	Max models.Decimal
+}
+
+// Allocate implements MatchingAlgorithm
+func (t TopFIFO) Allocate(a LevelAllocation) []models.Decimal {
+	shares := make([]models.Decimal, len(a.Orders))
+	rest := a.Quantity
+	top := -1
+	for i, order := range a.Orders {
+		if order == a.Top {
+			top = i
+		}
+	}
+	if top >= 0 {
+		shares[top] = models.MinDecimal(visibleQuantity(a.Orders[top]), rest)
+		if t.Max.Sign() > 0 {
+			shares[top] = models.MinDecimal(shares[top], t.Max)
+		}
+		rest = rest.Sub(shares[top])
+	}
+	var order []int
+	for i := range a.Orders {
+		if i != top {
+			order = append(order, i)
+		}
+	}
+	if top >= 0 {
+		order = append(order, top)
+	}
+	fillInOrder(a.Orders, shares, rest, order)
+	return shares
+}
+
+// LMM reserves Pct percent of the quantity, rounded down to whole lots, for the
+// orders of the lead market maker AccountID in time priority. The rest, including
+// whatever the lead market maker could not take, is allocated pro-rata among all
+// orders by their remaining visible quantity.
+type LMM struct {
+	AccountID int64
+	Pct       models.Decimal
+}
+
+// Allocate implements MatchingAlgorithm
+func (l LMM) Allocate(a LevelAllocation) []models.Decimal {
+	shares := make([]models.Decimal, len(a.Orders))
+	reserved := a.Quantity.Quo(a.Lot, 0).Mul(l.Pct).Quo(models.DecimalFromInt(100), 0).Mul(a.Lot)
+	var lmm []int
+	for i, order := range a.Orders {
+		if order.AccountID == l.AccountID {
+			lmm = append(lmm, i)
+		}
+	}
+	rest := a.Quantity.Sub(reserved)
+	if len(lmm) > 0 {
+		rest = rest.Add(fillInOrder(a.Orders, shares, reserved, lmm))
+	} else {
+		rest = a.Quantity
+	}
+	rest = proRata(a.Orders, shares, rest, a.Lot)
+	fillInOrder(a.Orders, shares, rest, nil)
+	return shares
+}
+
+// fillInOrder adds quantity to the shares of the orders at the given indexes, all
+// orders if nil, each up to its visible quantity, and returns what is left
+func fillInOrder(orders []*models.Order, shares []models.Decimal, quantity models.Decimal, indexes []int) models.Decimal {
+	if indexes == nil {
+		for i := range orders {
+			indexes = append(indexes, i)
+		}
+	}
+	for _, i := range indexes {
+		if quantity.Sign() <= 0 {
+			break
+		}
+		take := models.MinDecimal(visibleQuantity(orders[i]).Sub(shares[i]), quantity)
+		if take.Sign() > 0 {
+			shares[i] = shares[i].Add(take)
+			quantity = quantity.Sub(take)
+		}
+	}
+	return quantity
+}
+
+// proRata adds to each share quantity × the order's remaining visible lots / the
+// remaining visible lots of all orders, rounded down to whole lots, and returns
+// the residual
+func proRata(orders []*models.Order, shares []models.Decimal, quantity, lot models.Decimal) models.Decimal {
+	capacity := make([]models.Decimal, len(orders))
+	var total models.Decimal
+	for i, order := range orders {
+		capacity[i] = visibleQuantity(order).Sub(shares[i]).Quo(lot, 0)
+		total = total.Add(capacity[i])
+	}
+	if total.Sign() <= 0 {
+		return quantity
+	}
+	lots := quantity.Quo(lot, 0)
+	for i := range orders {
+		share := models.MinDecimal(lots.Mul(capacity[i]).Quo(total, 0), capacity[i]).Mul(lot)
+		shares[i] = shares[i].Add(share)
+		quantity = quantity.Sub(share)
+	}
+	return quantity
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"testing"
+
+	"golang-order-matching-system/models"
+)
+
+// goldenLevel returns the level of the matching algorithm golden scenarios: in time
+// priority order A of 10 (account 1), B of 30 (account 2) and C of 60 (account 3)
+func goldenLevel() []*models.Order {
+	return []*models.Order{
+		{ID: 1, AccountID: 1, Quantity: models.DecimalFromInt(10), RemainingQuantity: models.DecimalFromInt(10)},
+		{ID: 2, AccountID: 2, Quantity: models.DecimalFromInt(30), RemainingQuantity: models.DecimalFromInt(30)},
+		{ID: 3, AccountID: 3, Quantity: models.DecimalFromInt(60), RemainingQuantity: models.DecimalFromInt(60)},
+	}
+}
+
+func TestAllocateGolden(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm MatchingAlgorithm
+		lot       int64
+		quantity  int64
+		want      []int64
+	}{
+		{"fifo 7", FIFO{}, 1, 7, []int64{7, 0, 0}},
+		{"fifo 25", FIFO{}, 1, 25, []int64{10, 15, 0}},
+		{"fifo 100", FIFO{}, 1, 100, []int64{10, 30, 60}},
+		{"pro_rata 7", ProRata{}, 1, 7, []int64{1, 2, 4}},
+		{"pro_rata 25", ProRata{}, 1, 25, []int64{3, 7, 15}},
+		{"pro_rata 100", ProRata{}, 1, 100, []int64{10, 30, 60}},
+		{"pro_rata 25 lot 5", ProRata{}, 5, 25, []int64{5, 5, 15}},
+		{"fifo_top max 5 buy 7", TopFIFO{Max: models.DecimalFromInt(5)}, 1, 7, []int64{5, 2, 0}},
+		{"fifo_top max 5 buy 25", TopFIFO{Max: models.DecimalFromInt(5)}, 1, 25, []int64{5, 20, 0}},
+		{"fifo_top max 5 buy 100", TopFIFO{Max: models.DecimalFromInt(5)}, 1, 100, []int64{10, 30, 60}},
+		{"fifo_top unlimited 7", TopFIFO{}, 1, 7, []int64{7, 0, 0}},
+		{"fifo_top unlimited 25", TopFIFO{}, 1, 25, []int64{10, 15, 0}},
+		{"fifo_top unlimited 100", TopFIFO{}, 1, 100, []int64{10, 30, 60}},
+		{"lmm account 3 buy 7", LMM{AccountID: 3, Pct: models.DecimalFromInt(40)}, 1, 7, []int64{2, 1, 4}},
+		{"lmm account 3 buy 25", LMM{AccountID: 3, Pct: models.DecimalFromInt(40)}, 1, 25, []int64{2, 5, 18}},
+		{"lmm account 3 buy 100", LMM{AccountID: 3, Pct: models.DecimalFromInt(40)}, 1, 100, []int64{10, 30, 60}},
+		{"lmm account 1 buy 7", LMM{AccountID: 1, Pct: models.DecimalFromInt(40)}, 1, 7, []int64{3, 1, 3}},
+		{"lmm account 1 buy 25", LMM{AccountID: 1, Pct: models.DecimalFromInt(40)}, 1, 25, []int64{10, 5, 10}},
+		{"lmm account 1 buy 100", LMM{AccountID: 1, Pct: models.DecimalFromInt(40)}, 1, 100, []int64{10, 30, 60}},
+		{"lmm without maker", LMM{AccountID: 9, Pct: models.DecimalFromInt(40)}, 1, 25, []int64{3, 7, 15}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders := goldenLevel()
+			shares := tt.algorithm.Allocate(LevelAllocation{
+				Orders:   orders,
+				Quantity: models.DecimalFromInt(tt.quantity),
+				Lot:      models.DecimalFromInt(tt.lot),
+				Top:      orders[0],
+			})
+			if len(shares) != len(tt.want) {
+				t.Fatalf("got %d shares, want %d", len(shares), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !shares[i].Equal(models.DecimalFromInt(want)) {
+					t.Errorf("order %d: got %s, want %d", orders[i].ID, shares[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestAllocateIcebergVisibleQuantity(t *testing.T) {
+	display := models.DecimalFromInt(5)
+	orders := goldenLevel()
+	orders[0].DisplayQuantity = &display
+	orders[0].VisibleQuantity = display
+
+	for _, algorithm := range []MatchingAlgorithm{FIFO{}, ProRata{}, TopFIFO{}, LMM{AccountID: 1, Pct: models.DecimalFromInt(40)}} {
+		shares := algorithm.Allocate(LevelAllocation{
+			Orders:   orders,
+			Quantity: models.DecimalFromInt(25),
+			Lot:      models.DecimalFromInt(1),
+			Top:      orders[0],
+		})
+		var total models.Decimal
+		for _, share := range shares {
+			total = total.Add(share)
+		}
+		if !total.Equal(models.DecimalFromInt(25)) {
+			t.Errorf("%T: shares add up to %s, want 25", algorithm, total)
+		}
+		if shares[0].Cmp(display) > 0 {
+			t.Errorf("%T: iceberg got %s, more than its visible %s", algorithm, shares[0], display)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"database/sql"
+	"log"
+	"sort"
+	"time"
+
+	"golang-order-matching-system/models"
+)
+
+// auctionLimit returns the worst price an order accepts in an auction: its limit
+// price, or the protection price of a market order. Nil accepts any price.
+func auctionLimit(order *models.Order) *models.Decimal {
+	if order.Price != nil {
+		return order.Price
+	}
+	return order.ProtectionPrice
+}
+
+// acceptsPrice reports whether an order may trade at price in an auction
+func acceptsPrice(order *models.Order, price models.Decimal) bool {
+	limit := auctionLimit(order)
+	if limit == nil {
+		return true
+	}
+	if order.Side == "buy" {
+		return price.Cmp(*limit) <= 0
+	}
+	return price.Cmp(*limit) >= 0
+}
+
+// auctionPoint is the quantity of each side limited at one price of an auction
+type auctionPoint struct {
+	price    models.Decimal
+	bid, ask models.Decimal
+}
+
+// auctionPoints returns the limit prices of the resting orders of a book in
+// ascending order with the quantity of each side limited there, and the
+// quantity of each side that accepts any price
+func auctionPoints(book *symbolBook) (points []auctionPoint, anyBid, anyAsk models.Decimal) {
+	add := func(side string, limit *models.Decimal, quantity models.Decimal) {
+		switch {
+		case limit == nil && side == "buy":
+			anyBid = anyBid.Add(quantity)
+		case limit == nil:
+			anyAsk = anyAsk.Add(quantity)
+		case side == "buy":
+			points = append(points, auctionPoint{price: *limit, bid: quantity})
+		default:
+			points = append(points, auctionPoint{price: *limit, ask: quantity})
+		}
+	}
+	for _, side := range []*bookSide{book.bids, book.asks} {
+		for e := side.market.orders.Front(); e != nil; e = e.Next() {
+			order := e.Value.(*bookEntry).order
+			add(side.side, auctionLimit(order), order.RemainingQuantity)
+		}
+		for _, lvl := range side.levels {
+			var quantity models.Decimal
+			for e := lvl.orders.Front(); e != nil; e = e.Next() {
+				quantity = quantity.Add(e.Value.(*bookEntry).order.RemainingQuantity)
+			}
+			add(side.side, lvl.price, quantity)
+		}
+	}
+
+	sort.Slice(points, func(i, j int) bool { return points[i].price.Cmp(points[j].price) < 0 })
+	merged := points[:0]
+	for _, point := range points {
+		if n := len(merged); n > 0 && merged[n-1].price.Equal(point.price) {
+			merged[n-1].bid = merged[n-1].bid.Add(point.bid)
+			merged[n-1].ask = merged[n-1].ask.Add(point.ask)
+			continue
+		}
+		merged = append(merged, point)
+	}
+	return merged, anyBid, anyAsk
+}
+
+// indicativeAuction computes the single price at which the resting orders of a
+// book would uncross: the price executing the most volume, then leaving the
+// smallest imbalance, then closest to the reference price, then the lowest. The
+// price is nil when no orders cross. The candidate prices are the limit prices of
+// the orders, walked in ascending order with the cumulative volume of each side.
+func indicativeAuction(book *symbolBook, reference *models.Decimal) *models.Auction {
+	auction := &models.Auction{Symbol: book.symbol, At: time.Now()}
+	points, anyBid, anyAsk := auctionPoints(book)
+	if len(points) == 0 && reference != nil {
+		points = append(points, auctionPoint{price: *reference}) // unprotected market orders only
+	}
+
+	// A bid accepts every price up to its limit and an ask every price from its
+	// limit up, so going up the bid volume shrinks and the ask volume grows
+	remainingBids := anyBid
+	for _, point := range points {
+		remainingBids = remainingBids.Add(point.bid)
+	}
+	askVolume := anyAsk
+	var bestDistance models.Decimal
+	for _, point := range points {
+		price := point.price
+		bidVolume := remainingBids
+		remainingBids = remainingBids.Sub(point.bid)
+		askVolume = askVolume.Add(point.ask)
+
+		volume := models.MinDecimal(bidVolume, askVolume)
+		if volume.Sign() <= 0 {
+			continue
+		}
+		imbalance := bidVolume.Sub(askVolume).Abs()
+		var distance models.Decimal
+		if reference != nil {
+			distance = price.Sub(*reference).Abs()
+		}
+
+		better := auction.Price == nil
+		if !better {
+			switch {
+			case volume.Cmp(auction.Volume) != 0:
+				better = volume.Cmp(auction.Volume) > 0
+			case imbalance.Cmp(auction.Imbalance) != 0:
+				better = imbalance.Cmp(auction.Imbalance) < 0
+			case distance.Cmp(bestDistance) != 0:
+				better = distance.Cmp(bestDistance) < 0
+			default:
+				better = price.Cmp(*auction.Price) < 0
+			}
+		}
+		if better {
+			p := price
+			auction.Price = &p
+			auction.Volume = volume
+			auction.Imbalance = imbalance
+			bestDistance = distance
+		}
+	}
+	return auction
+}
+
+// refreshIndicative recomputes the indicative auction of a book that accumulates
+// orders without matching, and clears it once the book matches again
+func (b *symbolBook) refreshIndicative() {
+	b.indicative = nil
+	if !b.matching() {
+		b.indicative = indicativeAuction(b, b.lastPrice)
+	}
+}
+
+// firstAccepting returns the first order of a side in priority order that accepts price
+func firstAccepting(side *bookSide, price models.Decimal) *models.Order {
+	for e := side.market.orders.Front(); e != nil; e = e.Next() {
+		if order := e.Value.(*bookEntry).order; acceptsPrice(order, price) {
+			return order
+		}
+	}
+	// Levels are sorted best first, so only the best level can accept
+	if lvl := side.bestLevel(); lvl != nil {
+		if order := lvl.orders.Front().Value.(*bookEntry).order; acceptsPrice(order, price) {
+			return order
+		}
+	}
+	return nil
+}
+
+// uncross executes all resting orders that accept the auction price against each
+// other in priority order, every trade at that price. The later queued order of
+// each pair is the taker. The auction is updated with the volume actually traded.
+func (ob *OrderBook) uncross(book *symbolBook, auction *models.Auction, tx *sql.Tx) error {
+	auction.Volume = models.Decimal{}
+	if auction.Price == nil {
+		return nil
+	}
+	price := *auction.Price
+
+	for {
+		bid := firstAccepting(book.bids, price)
+		ask := firstAccepting(book.asks, price)
+		if bid == nil || ask == nil {
+			break
+		}
+		taker, maker := bid, ask
+		if ask.QueuedAt.After(bid.QueuedAt) {
+			taker, maker = ask, bid
+		}
+
+		if isSelfTrade(taker, maker) {
+			if _, err := ob.preventSelfTrade(book, taker, maker, tx); err != nil {
+				return err
+			}
+			if taker.Status == OrderStatusCanceled {
+				book.remove(taker.ID)
+			}
+			continue
+		}
+		if !canAfford(bid, price) {
+			if err := ob.cancelRemainder(book, bid, tx); err != nil {
+				return err
+			}
+			book.remove(bid.ID)
+			log.Printf("Buy order %d canceled in the %s auction, its hold does not cover %s", bid.ID, book.symbol, price)
+			continue
+		}
+
+		quantity := models.MinDecimal(visibleQuantity(bid), visibleQuantity(ask))
+		if err := ob.fill(book, taker, maker, price, quantity, tx); err != nil {
+			return err
+		}
+		auction.Volume = auction.Volume.Add(quantity)
+		auction.Trades++
+	}
+	auction.At = time.Now()
+	log.Printf("Auction for %s uncrossed %s in %d trades at %s", book.symbol, auction.Volume, auction.Trades, price)
+	return nil
+}
@@ -0,0 +1,273 @@
+package engine
+
+import (
+	"container/list"
+	"sort"
+	"time"
+
+	"golang-order-matching-system/models"
+)
+
+// priceLevel holds the resting orders at a single price in time priority
+type priceLevel struct {
+	price  *models.Decimal // nil for the queue of resting market orders
+	orders *list.List
+}
+
+// bookEntry locates a resting order inside its price level
+type bookEntry struct {
+	order *models.Order
+	side  *bookSide
+	level *priceLevel
+	elem  *list.Element
+}
+
+// bookSide keeps the price levels of one side sorted from best to worst
+type bookSide struct {
+	side   string
+	market *priceLevel
+	levels []*priceLevel
+	top    *models.Order // the order that set the best price, until it leaves the book or is bettered
+}
+
+func newBookSide(side string) *bookSide {
+	return &bookSide{
+		side:   side,
+		market: &priceLevel{orders: list.New()},
+	}
+}
+
+// better reports whether price a has priority over price b on this side
+func (s *bookSide) better(a, b models.Decimal) bool {
+	if s.side == "buy" {
+		return a.Cmp(b) > 0
+	}
+	return a.Cmp(b) < 0
+}
+
+// search returns the index of the level for price, or where it would be inserted
+func (s *bookSide) search(price models.Decimal) (int, bool) {
+	i := sort.Search(len(s.levels), func(i int) bool {
+		return !s.better(*s.levels[i].price, price)
+	})
+	return i, i < len(s.levels) && s.levels[i].price.Equal(price)
+}
+
+// level returns the level for price, creating it if needed
+func (s *bookSide) level(price models.Decimal) *priceLevel {
+	i, found := s.search(price)
+	if found {
+		return s.levels[i]
+	}
+	p := price
+	lvl := &priceLevel{price: &p, orders: list.New()}
+	s.levels = append(s.levels, nil)
+	copy(s.levels[i+1:], s.levels[i:])
+	s.levels[i] = lvl
+	return lvl
+}
+
+// push appends an order to the back of the queue at its price
+func (s *bookSide) push(order *models.Order) *bookEntry {
+	return s.pushAt(order, order.Price)
+}
+
+// pushAt appends an order to the back of the queue at the given price, or to the
+// market queue when price is nil
+func (s *bookSide) pushAt(order *models.Order, price *models.Decimal) *bookEntry {
+	lvl := s.market
+	if price != nil {
+		lvl = s.level(*price)
+	}
+	entry := &bookEntry{order: order, side: s, level: lvl}
+	entry.elem = lvl.orders.PushBack(entry)
+	return entry
+}
+
+// remove takes an entry out of its level and drops the level once empty
+func (s *bookSide) remove(entry *bookEntry) {
+	entry.level.orders.Remove(entry.elem)
+	if entry.level == s.market || entry.level.orders.Len() > 0 {
+		return
+	}
+	if i, found := s.search(*entry.level.price); found {
+		s.levels = append(s.levels[:i], s.levels[i+1:]...)
+	}
+}
+
+// bestLevel returns the best priced level, or nil if the side has none
+func (s *bookSide) bestLevel() *priceLevel {
+	if len(s.levels) == 0 {
+		return nil
+	}
+	return s.levels[0]
+}
+
+// orderList returns the resting orders in priority order, market orders first
+func (s *bookSide) orderList() []*models.Order {
+	var orders []*models.Order
+	for _, lvl := range append([]*priceLevel{s.market}, s.levels...) {
+		for e := lvl.orders.Front(); e != nil; e = e.Next() {
+			orders = append(orders, e.Value.(*bookEntry).order)
+		}
+	}
+	return orders
+}
+
+// symbolBook is the in-memory book of resting orders for one symbol. It is only
+// touched by the sequencer goroutine consuming its commands channel.
+type symbolBook struct {
+	symbol     string
+	bids       *bookSide
+	asks       *bookSide
+	buyStops   *bookSide // pending buy stops, lowest stop price first
+	sellStops  *bookSide // pending sell stops, highest stop price first
+	index      map[int64]*bookEntry
+	openOrders map[int64]int            // resting orders of each account, stops included
+	lastPrice  *models.Decimal          // price of the last trade, nil before the first one
+	halt       *models.Halt             // active trading halt, nil while trading
+	phase      string                   // trading session phase, see models.Phase*
+	indicative *models.Auction          // indicative uncross while orders accumulate, nil while matching
+	window     priceWindow              // recent trade prices watched by the circuit breaker
+	seq        int64                    // engine sequence number, advanced by every mutating command
+	trades     []*models.Trade          // trades of the current command, published once it commits
+	reports    []models.ExecutionReport // execution reports of the current command, written when it commits
+	depth      depthState               // depth as last published to market data subscribers
+	commands   chan command
+
+	subscribers map[*Subscriber]map[string]bool // market data channels of each subscriber
+}
+
+func newSymbolBook(symbol string) *symbolBook {
+	b := &symbolBook{
+		symbol:      symbol,
+		phase:       models.PhaseContinuous,
+		commands:    make(chan command, commandQueueSize),
+		subscribers: make(map[*Subscriber]map[string]bool),
+	}
+	b.reset(nil)
+	return b
+}
+
+// reset replaces the contents of the book with the given orders in arrival order
+func (b *symbolBook) reset(orders []models.Order) {
+	b.bids = newBookSide("buy")
+	b.asks = newBookSide("sell")
+	// Stops trigger in the order the price reaches them, which is the
+	// priority order of the opposite side of the book
+	b.buyStops = newBookSide("sell")
+	b.sellStops = newBookSide("buy")
+	b.index = make(map[int64]*bookEntry)
+	b.openOrders = make(map[int64]int)
+	for i := range orders {
+		b.add(&orders[i])
+	}
+}
+
+// isIceberg reports whether an order shows only a slice of its remaining quantity
+func isIceberg(order *models.Order) bool {
+	return order.DisplayQuantity != nil
+}
+
+// visibleQuantity returns the quantity of a resting order that is shown and matchable at its queue position
+func visibleQuantity(order *models.Order) models.Decimal {
+	if isIceberg(order) {
+		return order.VisibleQuantity
+	}
+	return order.RemainingQuantity
+}
+
+// refreshSlice reloads the visible slice of an iceberg from its hidden reserve
+func refreshSlice(order *models.Order) {
+	order.VisibleQuantity = models.MinDecimal(*order.DisplayQuantity, order.RemainingQuantity)
+	order.QueuedAt = time.Now()
+}
+
+// isStop reports whether an order is a stop waiting for its trigger
+func isStop(order *models.Order) bool {
+	return order.Type == "stop" || order.Type == "stop_limit"
+}
+
+// sideOf returns the side an order with the given side rests on
+func (b *symbolBook) sideOf(side string) *bookSide {
+	if side == "buy" {
+		return b.bids
+	}
+	return b.asks
+}
+
+// oppositeOf returns the side an order with the given side matches against
+func (b *symbolBook) oppositeOf(side string) *bookSide {
+	if side == "buy" {
+		return b.asks
+	}
+	return b.bids
+}
+
+// add rests an order at the back of its price level, or in the trigger book for stops
+func (b *symbolBook) add(order *models.Order) {
+	b.openOrders[order.AccountID]++
+	if isStop(order) {
+		stops := b.buyStops
+		if order.Side != "buy" {
+			stops = b.sellStops
+		}
+		b.index[order.ID] = stops.pushAt(order, order.StopPrice)
+		return
+	}
+	side := b.sideOf(order.Side)
+	entry := side.push(order)
+	b.index[order.ID] = entry
+	// An order opening a new best level becomes the top order of its side
+	if order.Price != nil && entry.level == side.bestLevel() && entry.level.orders.Len() == 1 {
+		side.top = order
+	}
+}
+
+// remove takes an order out of the book, reporting whether it was resting
+func (b *symbolBook) remove(orderID int64) bool {
+	entry, ok := b.index[orderID]
+	if !ok {
+		return false
+	}
+	entry.side.remove(entry)
+	if entry.side.top == entry.order {
+		entry.side.top = nil
+	}
+	delete(b.index, orderID)
+	account := entry.order.AccountID
+	b.openOrders[account]--
+	if b.openOrders[account] == 0 {
+		delete(b.openOrders, account)
+	}
+	return true
+}
+
+// matching reports whether incoming orders match on arrival: the symbol trades
+// continuously and is not halted
+func (b *symbolBook) matching() bool {
+	return b.halt == nil && b.phase == models.PhaseContinuous
+}
+
+// nextTriggered returns the first pending stop whose stop price has been reached
+// by the last trade price, or nil. Stops do not trigger while the book is not matching.
+func (b *symbolBook) nextTriggered() *models.Order {
+	if b.lastPrice == nil || !b.matching() {
+		return nil
+	}
+	if lvl := b.buyStops.bestLevel(); lvl != nil && b.lastPrice.Cmp(*lvl.price) >= 0 {
+		return lvl.orders.Front().Value.(*bookEntry).order
+	}
+	if lvl := b.sellStops.bestLevel(); lvl != nil && b.lastPrice.Cmp(*lvl.price) <= 0 {
+		return lvl.orders.Front().Value.(*bookEntry).order
+	}
+	return nil
+}
+
+// get returns a resting order by ID
+func (b *symbolBook) get(orderID int64) *models.Order {
+	if entry, ok := b.index[orderID]; ok {
+		return entry.order
+	}
+	return nil
+}
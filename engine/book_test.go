@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"testing"
+
+	"golang-order-matching-system/models"
+)
+
+// restingOrder returns an open limit order of 1 at price
+func restingOrder(id int64, side, price string) *models.Order {
+	p, err := models.ParseDecimal(price)
+	if err != nil {
+		panic(err)
+	}
+	return &models.Order{
+		ID:                id,
+		Side:              side,
+		Type:              "limit",
+		Price:             &p,
+		Quantity:          models.DecimalFromInt(1),
+		RemainingQuantity: models.DecimalFromInt(1),
+		Status:            OrderStatusOpen,
+	}
+}
+
+// levelPrices returns the prices of the levels of a side, best first
+func levelPrices(side *bookSide) []string {
+	var prices []string
+	for _, lvl := range side.levels {
+		prices = append(prices, lvl.price.String())
+	}
+	return prices
+}
+
+// orderIDs returns the IDs of the resting orders of a side in priority order
+func orderIDs(side *bookSide) []int64 {
+	var ids []int64
+	for _, order := range side.orderList() {
+		ids = append(ids, order.ID)
+	}
+	return ids
+}
+
+func equalSlices[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBookLevelOrdering(t *testing.T) {
+	book := newSymbolBook("TEST")
+	book.add(restingOrder(1, "buy", "99.00"))
+	book.add(restingOrder(2, "buy", "101.00"))
+	book.add(restingOrder(3, "buy", "100.00"))
+	book.add(restingOrder(4, "buy", "101.00"))
+	book.add(restingOrder(5, "sell", "103.00"))
+	book.add(restingOrder(6, "sell", "102.00"))
+	book.add(restingOrder(7, "sell", "102.00"))
+	book.add(restingOrder(8, "sell", "104.00"))
+
+	if got, want := levelPrices(book.bids), []string{"101.00", "100.00", "99.00"}; !equalSlices(got, want) {
+		t.Errorf("bid levels: got %v, want %v", got, want)
+	}
+	if got, want := levelPrices(book.asks), []string{"102.00", "103.00", "104.00"}; !equalSlices(got, want) {
+		t.Errorf("ask levels: got %v, want %v", got, want)
+	}
+	// Orders at the same price keep time priority
+	if got, want := orderIDs(book.bids), []int64{2, 4, 3, 1}; !equalSlices(got, want) {
+		t.Errorf("bid priority: got %v, want %v", got, want)
+	}
+	if got, want := orderIDs(book.asks), []int64{6, 7, 5, 8}; !equalSlices(got, want) {
+		t.Errorf("ask priority: got %v, want %v", got, want)
+	}
+	// The order that opened the best level holds top-order priority
+	if book.bids.top == nil || book.bids.top.ID != 2 {
+		t.Errorf("bid top: got %v, want order 2", book.bids.top)
+	}
+	if book.asks.top == nil || book.asks.top.ID != 6 {
+		t.Errorf("ask top: got %v, want order 6", book.asks.top)
+	}
+}
+
+func TestBookMarketAndStopOrders(t *testing.T) {
+	book := newSymbolBook("TEST")
+	book.add(restingOrder(1, "sell", "101.00"))
+	market := restingOrder(2, "sell", "0")
+	market.Type = "market"
+	market.Price = nil
+	book.add(market)
+	stop := restingOrder(3, "sell", "0")
+	stop.Type = "stop"
+	stop.Price = nil
+	stopPrice := models.DecimalFromInt(95)
+	stop.StopPrice = &stopPrice
+	book.add(stop)
+
+	if got, want := orderIDs(book.asks), []int64{2, 1}; !equalSlices(got, want) {
+		t.Errorf("ask priority: got %v, want %v", got, want)
+	}
+	if got, want := orderIDs(book.sellStops), []int64{3}; !equalSlices(got, want) {
+		t.Errorf("sell stops: got %v, want %v", got, want)
+	}
+	if book.get(3) != stop {
+		t.Errorf("stop order is not indexed")
+	}
+}
+
+func TestBookRemove(t *testing.T) {
+	book := newSymbolBook("TEST")
+	book.add(restingOrder(1, "buy", "100.00"))
+	book.add(restingOrder(2, "buy", "100.00"))
+	book.add(restingOrder(3, "buy", "99.00"))
+
+	if !book.remove(1) {
+		t.Fatal("remove(1) reported the order was not resting")
+	}
+	if got, want := levelPrices(book.bids), []string{"100.00", "99.00"}; !equalSlices(got, want) {
+		t.Errorf("levels after removing one of two orders: got %v, want %v", got, want)
+	}
+	if book.bids.top != nil {
+		t.Errorf("top order kept after it left the book: %v", book.bids.top.ID)
+	}
+
+	if !book.remove(2) {
+		t.Fatal("remove(2) reported the order was not resting")
+	}
+	if got, want := levelPrices(book.bids), []string{"99.00"}; !equalSlices(got, want) {
+		t.Errorf("levels after emptying 100.00: got %v, want %v", got, want)
+	}
+	if got, want := orderIDs(book.bids), []int64{3}; !equalSlices(got, want) {
+		t.Errorf("bids after removal: got %v, want %v", got, want)
+	}
+
+	if book.remove(2) {
+		t.Error("remove(2) twice reported the order as resting")
+	}
+	if book.get(2) != nil {
+		t.Error("removed order is still indexed")
+	}
+
+	book.remove(3)
+	if book.bids.bestLevel() != nil {
+		t.Errorf("empty side still has levels: %v", levelPrices(book.bids))
+	}
+}
+
+func TestBookOpenOrderCount(t *testing.T) {
+	book := newSymbolBook("TEST")
+	for id, account := range []int64{1, 1, 2} {
+		order := restingOrder(int64(id+1), "buy", "100.00")
+		order.AccountID = account
+		book.add(order)
+	}
+	if got := book.openOrders[1]; got != 2 {
+		t.Errorf("account 1: got %d open orders, want 2", got)
+	}
+
+	book.remove(1)
+	book.remove(1)
+	if got := book.openOrders[1]; got != 1 {
+		t.Errorf("account 1 after removing an order twice: got %d open orders, want 1", got)
+	}
+	book.remove(3)
+	if _, ok := book.openOrders[2]; ok {
+		t.Error("account 2 is still counted after its last order left the book")
+	}
+}
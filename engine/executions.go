@@ -0,0 +1,224 @@
+package engine
+
+import (
+	"database/sql"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang-order-matching-system/db"
+	"golang-order-matching-system/models"
+)
+
+// maxReasonLength bounds the reject reason stored with a report
+const maxReasonLength = 255
+
+// commitShards is the number of locks report commits are spread over by account
+const commitShards = 64
+
+// ExecutionSubscriber receives the execution reports of one account. Like market
+// data subscribers it is never waited for: one whose buffer is full is dropped
+// and its Done channel closed.
+type ExecutionSubscriber struct {
+	accountID int64
+	reports   chan models.ExecutionReport
+	done      chan struct{}
+	once      sync.Once
+	dropped   atomic.Bool
+}
+
+// Reports returns the channel reports are delivered on
+func (s *ExecutionSubscriber) Reports() <-chan models.ExecutionReport {
+	return s.reports
+}
+
+// Done is closed once the subscriber is closed or dropped
+func (s *ExecutionSubscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Dropped reports whether the subscriber was dropped for falling behind
+func (s *ExecutionSubscriber) Dropped() bool {
+	return s.dropped.Load()
+}
+
+// Close ends the subscription
+func (s *ExecutionSubscriber) Close() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// send queues a report without blocking and drops the subscriber when its buffer is full
+func (s *ExecutionSubscriber) send(report models.ExecutionReport) {
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	select {
+	case s.reports <- report:
+	default:
+		s.dropped.Store(true)
+		s.Close()
+		log.Printf("Execution report subscriber of account %d dropped, %d reports behind", s.accountID, len(s.reports))
+	}
+}
+
+// ExecutionHub stores the execution reports of all symbols and delivers them to
+// the subscribers of their accounts. Commands that report to a common account
+// write and commit their reports one at a time, so the sequence numbers of an
+// account follow commit order and a client that resumes after the last sequence
+// number it saw misses nothing. Commands of unrelated accounts commit in parallel.
+type ExecutionHub struct {
+	shards      [commitShards]sync.Mutex // serialize the report commits of the accounts they cover
+	mu          sync.Mutex               // guards subscribers
+	subscribers map[int64]map[*ExecutionSubscriber]bool
+}
+
+// NewExecutionHub creates a hub without subscribers
+func NewExecutionHub() *ExecutionHub {
+	return &ExecutionHub{subscribers: make(map[int64]map[*ExecutionSubscriber]bool)}
+}
+
+// Subscribe registers a subscriber for the reports of an account committed from
+// now on. Reports committed before it returns are already in the database.
+func (h *ExecutionHub) Subscribe(accountID int64) *ExecutionSubscriber {
+	sub := &ExecutionSubscriber{
+		accountID: accountID,
+		reports:   make(chan models.ExecutionReport, subscriberBuffer),
+		done:      make(chan struct{}),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[accountID] == nil {
+		h.subscribers[accountID] = make(map[*ExecutionSubscriber]bool)
+	}
+	h.subscribers[accountID][sub] = true
+	return sub
+}
+
+// Unsubscribe closes a subscriber and forgets it
+func (h *ExecutionHub) Unsubscribe(sub *ExecutionSubscriber) {
+	sub.Close()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers[sub.accountID], sub)
+	if len(h.subscribers[sub.accountID]) == 0 {
+		delete(h.subscribers, sub.accountID)
+	}
+}
+
+// commit writes reports within tx, commits it and delivers the reports. A nil tx
+// stores reports that belong to no transaction, such as rejections. The
+// transaction is rolled back if a report cannot be written.
+func (h *ExecutionHub) commit(tx *sql.Tx, reports []models.ExecutionReport) error {
+	unlock := h.lockAccounts(reports)
+	defer unlock()
+	for i := range reports {
+		if err := db.CreateExecutionReportTx(&reports[i], tx); err != nil {
+			if tx != nil {
+				tx.Rollback()
+			}
+			return err
+		}
+	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, report := range reports {
+		for sub := range h.subscribers[report.AccountID] {
+			sub.send(report)
+		}
+	}
+	return nil
+}
+
+// lockAccounts locks the shards of the accounts of reports in ascending order, so
+// commands sharing shards cannot deadlock, and returns a function that unlocks them
+func (h *ExecutionHub) lockAccounts(reports []models.ExecutionReport) func() {
+	var held [commitShards]bool
+	for _, report := range reports {
+		held[uint64(report.AccountID)%commitShards] = true
+	}
+	for i := range held {
+		if held[i] {
+			h.shards[i].Lock()
+		}
+	}
+	return func() {
+		for i := range held {
+			if held[i] {
+				h.shards[i].Unlock()
+			}
+		}
+	}
+}
+
+// commit commits the transaction of the current command of a book together with
+// the execution reports it produced
+func (ob *OrderBook) commit(book *symbolBook, tx *sql.Tx) error {
+	return ob.Executions.commit(tx, book.reports)
+}
+
+// newReport returns an execution report of the current state of an order
+func newReport(order *models.Order, execType string) models.ExecutionReport {
+	report := models.ExecutionReport{
+		AccountID:         order.AccountID,
+		OrderID:           order.ID,
+		Symbol:            order.Symbol,
+		Side:              order.Side,
+		OrderType:         order.Type,
+		ExecType:          execType,
+		Status:            order.Status,
+		Quantity:          order.Quantity,
+		FilledQuantity:    order.Quantity.Sub(order.RemainingQuantity),
+		RemainingQuantity: order.RemainingQuantity,
+		CreatedAt:         time.Now(),
+	}
+	if order.Price != nil {
+		price := *order.Price
+		report.Price = &price
+	}
+	return report
+}
+
+// report records an execution report of an order for the current command
+func (b *symbolBook) report(order *models.Order, execType string) {
+	b.reports = append(b.reports, newReport(order, execType))
+}
+
+// reportFill records the execution report of one side of a trade
+func (b *symbolBook) reportFill(order *models.Order, trade *models.Trade) {
+	execType := models.ExecPartiallyFilled
+	if order.Status == OrderStatusFilled {
+		execType = models.ExecFilled
+	}
+	report := newReport(order, execType)
+	price, quantity := trade.Price, trade.Quantity
+	report.LastPrice = &price
+	report.LastQuantity = &quantity
+	report.TradeID = trade.ID
+	b.reports = append(b.reports, report)
+}
+
+// reportRejection stores and delivers the execution report of a new order the
+// engine refused. The order was never stored, so the report has no order ID.
+func (ob *OrderBook) reportRejection(order *models.Order, rejection *RejectError) {
+	report := newReport(order, models.ExecRejected)
+	report.OrderID = 0
+	report.Status = models.ExecRejected
+	report.FilledQuantity = models.Decimal{}
+	report.RemainingQuantity = models.Decimal{}
+	report.RejectCode = rejection.Code
+	report.Reason = rejection.Message
+	if len(report.Reason) > maxReasonLength {
+		report.Reason = report.Reason[:maxReasonLength]
+	}
+	if err := ob.Executions.commit(nil, []models.ExecutionReport{report}); err != nil {
+		log.Printf("Failed to report rejection of order for %s from account %d: %v", order.Symbol, order.AccountID, err)
+	}
+}
@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"golang-order-matching-system/db"
+	"golang-order-matching-system/models"
+)
+
+// feeVolumeWindow is the trailing period whose traded volume selects a fee tier
+const feeVolumeWindow = 30 * 24 * time.Hour
+
+// ErrInvalidFeeSchedule is returned for fee schedules that cannot be applied
+var ErrInvalidFeeSchedule = errors.New("invalid fee schedule")
+
+// volumeKey identifies the trailing volume of an account on a symbol
+type volumeKey struct {
+	accountID int64
+	symbol    string
+}
+
+// FeeSchedules caches the fee tiers of every instrument and the trailing volumes
+// that select the tier of an account. Volumes are recomputed from the trades
+// table by Refresh and grow with every trade in between, so matching never
+// queries the database for them.
+type FeeSchedules struct {
+	mu          sync.RWMutex
+	schedules   map[string][]models.FeeTier
+	volumes     map[volumeKey]models.Decimal
+	refreshedAt time.Time
+}
+
+// NewFeeSchedules creates an empty set of fee schedules, under which trading is free
+func NewFeeSchedules() *FeeSchedules {
+	return &FeeSchedules{
+		schedules: make(map[string][]models.FeeTier),
+		volumes:   make(map[volumeKey]models.Decimal),
+	}
+}
+
+// Load reads all fee schedules and trailing volumes from the database
+func (f *FeeSchedules) Load() error {
+	schedules, err := db.GetFeeTiers()
+	if err != nil {
+		log.Printf("Failed to load fee schedules: %v", err)
+		return err
+	}
+	f.mu.Lock()
+	f.schedules = schedules
+	f.mu.Unlock()
+	log.Printf("Fee schedules loaded for %d instruments", len(schedules))
+	return f.Refresh()
+}
+
+// Refresh recomputes the trailing volumes of all accounts. Accounts without
+// trades in the window are dropped, as is the volume of trades whose command
+// was rolled back after they were counted.
+func (f *FeeSchedules) Refresh() error {
+	now := time.Now()
+	traded, err := db.GetTradedVolumes(now.Add(-feeVolumeWindow))
+	if err != nil {
+		return err
+	}
+	volumes := make(map[volumeKey]models.Decimal, len(traded))
+	for _, v := range traded {
+		volumes[volumeKey{v.AccountID, v.Symbol}] = v.Volume
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.volumes = volumes
+	f.refreshedAt = now
+	return nil
+}
+
+// Run refreshes the trailing volumes every interval
+func (f *FeeSchedules) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := f.Refresh(); err != nil {
+			log.Printf("Failed to refresh traded volumes: %v", err)
+		}
+	}
+}
+
+// addVolume counts a trade towards the trailing volume of both its accounts
+// until the next refresh
+func (f *FeeSchedules) addVolume(trade *models.Trade) {
+	notional := tradeCost(trade)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, accountID := range []int64{trade.BuyAccountID, trade.SellAccountID} {
+		key := volumeKey{accountID, trade.Symbol}
+		f.volumes[key] = f.volumes[key].Add(notional)
+	}
+}
+
+// Schedule returns the fee tiers of a symbol ordered by minimum volume
+func (f *FeeSchedules) Schedule(symbol string) []models.FeeTier {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return append([]models.FeeTier(nil), f.schedules[symbol]...)
+}
+
+// Set validates and replaces the fee schedule of a symbol. An empty schedule makes trading free.
+func (f *FeeSchedules) Set(symbol string, tiers []models.FeeTier) error {
+	if err := validateFeeTiers(tiers); err != nil {
+		return err
+	}
+	if err := db.ReplaceFeeTiers(symbol, tiers); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(tiers) == 0 {
+		delete(f.schedules, symbol)
+	} else {
+		f.schedules[symbol] = tiers
+	}
+	return nil
+}
+
+// validateFeeTiers checks that tiers start at zero volume, are strictly ordered
+// and never pay out more in rebates than the taker rate of the same tier collects
+func validateFeeTiers(tiers []models.FeeTier) error {
+	one := models.DecimalFromInt(1)
+	for i, tier := range tiers {
+		switch {
+		case i == 0 && !tier.MinVolume.IsZero():
+			return fmt.Errorf("%w: the first tier must have min_volume 0", ErrInvalidFeeSchedule)
+		case i > 0 && tier.MinVolume.Cmp(tiers[i-1].MinVolume) <= 0:
+			return fmt.Errorf("%w: tiers must be ordered by strictly increasing min_volume", ErrInvalidFeeSchedule)
+		case tier.TakerRate.Sign() < 0 || tier.TakerRate.Cmp(one) >= 0:
+			return fmt.Errorf("%w: taker_rate must be at least 0 and below 1", ErrInvalidFeeSchedule)
+		case tier.MakerRate.Cmp(one) >= 0:
+			return fmt.Errorf("%w: maker_rate must be below 1", ErrInvalidFeeSchedule)
+		case tier.MakerRate.Add(tier.TakerRate).Sign() < 0:
+			return fmt.Errorf("%w: a maker rebate must not exceed the taker rate of its tier", ErrInvalidFeeSchedule)
+		case tier.MakerRate.Scale() > 6 || tier.TakerRate.Scale() > 6:
+			return fmt.Errorf("%w: rates have at most 6 decimal places", ErrInvalidFeeSchedule)
+		}
+	}
+	return nil
+}
+
+// Volume returns the trailing 30-day quote volume of an account on a symbol as
+// of the last refresh plus the trades since
+func (f *FeeSchedules) Volume(accountID int64, symbol string) models.Decimal {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.volumes[volumeKey{accountID, symbol}]
+}
+
+// Fees returns the tier an account currently trades at on a symbol, together
+// with its trailing volume and the full schedule
+func (f *FeeSchedules) Fees(accountID int64, symbol string) *models.AccountFees {
+	volume := f.Volume(accountID, symbol)
+	f.mu.RLock()
+	computedAt := f.refreshedAt
+	f.mu.RUnlock()
+	fees := &models.AccountFees{
+		AccountID:  accountID,
+		Symbol:     symbol,
+		Volume30d:  volume,
+		Schedule:   f.Schedule(symbol),
+		TierIndex:  -1,
+		ComputedAt: computedAt,
+	}
+	for i, tier := range fees.Schedule {
+		if volume.Cmp(tier.MinVolume) >= 0 {
+			fees.Tier = tier
+			fees.TierIndex = i
+		}
+	}
+	return fees
+}
+
+// rate returns the maker or taker rate an account pays on a symbol
+func (f *FeeSchedules) rate(accountID int64, symbol string, maker bool) models.Decimal {
+	fees := f.Fees(accountID, symbol)
+	if maker {
+		return fees.Tier.MakerRate
+	}
+	return fees.Tier.TakerRate
+}
+
+// applyFees computes the fees of both sides of a trade: the buyer pays in the
+// base asset it receives, the seller in the quote asset it receives
+func (ob *OrderBook) applyFees(trade *models.Trade) error {
+	base, quote, err := ob.assetsOf(trade.Symbol)
+	if err != nil {
+		return err
+	}
+	buyRate := ob.Fees.rate(trade.BuyAccountID, trade.Symbol, trade.TakerSide != "buy")
+	sellRate := ob.Fees.rate(trade.SellAccountID, trade.Symbol, trade.TakerSide != "sell")
+	trade.BuyFee = trade.Quantity.MulRound(buyRate, holdScale, models.RoundHalfEven)
+	trade.BuyFeeAsset = base
+	trade.SellFee = tradeCost(trade).MulRound(sellRate, holdScale, models.RoundHalfEven)
+	trade.SellFeeAsset = quote
+	return nil
+}
@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"database/sql"
+	"log"
+
+	"golang-order-matching-system/db"
+	"golang-order-matching-system/models"
+)
+
+// holdScale is the precision of holds, trade costs and fees, matching the balances
+// table. Holds round up and trade costs round down, so the hold of a buy order
+// always covers the fills it pays for; fees round half to even.
+const holdScale = 8
+
+// assetsOf returns the base and quote asset of a symbol
+func (ob *OrderBook) assetsOf(symbol string) (string, string, error) {
+	inst, ok := ob.Instruments.Get(symbol)
+	if !ok {
+		return "", "", reject(RejectUnknownSymbol, "Unknown symbol: %s", symbol)
+	}
+	return inst.BaseAsset, inst.QuoteAsset, nil
+}
+
+// holdAsset returns the asset an order holds: the quote asset for buys and the base asset for sells
+func (ob *OrderBook) holdAsset(order *models.Order) (string, error) {
+	base, quote, err := ob.assetsOf(order.Symbol)
+	if order.Side == "buy" {
+		return quote, err
+	}
+	return base, err
+}
+
+// collarPrice returns the worst price a market order referencing price may trade
+// at: the instrument's market collar above it for buys and below it for sells
+func (ob *OrderBook) collarPrice(symbol, side string, price models.Decimal) models.Decimal {
+	pct := DefaultMarketCollarPct
+	if inst, ok := ob.Instruments.Get(symbol); ok {
+		pct = inst.MarketCollarPct
+	}
+	hundred := models.DecimalFromInt(100)
+	if side != "buy" {
+		pct = pct.Neg()
+	}
+	return price.MulQuo(hundred.Add(pct), hundred, price.Scale())
+}
+
+// protectionPrice returns the collar price of a market order around the best
+// opposite price, or the last trade price when the opposite side is empty. It is
+// nil when neither is known.
+func (ob *OrderBook) protectionPrice(book *symbolBook, order *models.Order) *models.Decimal {
+	reference := book.lastPrice
+	if lvl := book.oppositeOf(order.Side).bestLevel(); lvl != nil {
+		reference = lvl.price
+	}
+	if reference == nil {
+		return nil
+	}
+	price := ob.collarPrice(order.Symbol, order.Side, *reference)
+	return &price
+}
+
+// requiredHold returns the funds an order needs for its remaining quantity: the
+// quantity for sells, price × quantity for priced buys, the quantity at the
+// protection price for market buys and at the collar above the stop price for
+// stop buys. A hold too large to represent is rejected; every fill of the order
+// costs at most its hold, so matching never overflows afterwards.
+func (ob *OrderBook) requiredHold(order *models.Order) (models.Decimal, error) {
+	if order.Side != "buy" {
+		return order.RemainingQuantity, nil
+	}
+	var price *models.Decimal
+	switch {
+	case order.Price != nil:
+		price = order.Price
+	case order.ProtectionPrice != nil:
+		price = order.ProtectionPrice
+	case order.StopPrice != nil:
+		collar := ob.collarPrice(order.Symbol, order.Side, *order.StopPrice)
+		price = &collar
+	default:
+		return models.Decimal{}, nil
+	}
+	hold, err := price.CheckedMulRound(order.RemainingQuantity, holdScale, models.RoundUp)
+	if err != nil {
+		return models.Decimal{}, reject(RejectValueOutOfRange, "Order value of %s at %s is out of range", order.RemainingQuantity, price)
+	}
+	return hold, nil
+}
+
+// holdPortion returns the part of an order's hold that covers quantity of its
+// remaining quantity. The last portion is the whole hold, so rounding never
+// leaves funds behind.
+func holdPortion(order *models.Order, quantity models.Decimal) models.Decimal {
+	if quantity.Cmp(order.RemainingQuantity) >= 0 {
+		return order.Hold
+	}
+	if order.Side != "buy" {
+		return models.MinDecimal(quantity, order.Hold)
+	}
+	if order.Price != nil {
+		return models.MinDecimal(order.Price.MulRound(quantity, holdScale, models.RoundDown), order.Hold)
+	}
+	return order.Hold.MulQuo(quantity, order.RemainingQuantity, holdScale)
+}
+
+// canAfford reports whether the hold of a buy order covers its remaining quantity
+// at price exactly, so every portion of the hold covers the cost of its fill
+func canAfford(bid *models.Order, price models.Decimal) bool {
+	required, err := price.CheckedMulRound(bid.RemainingQuantity, holdScale, models.RoundUp)
+	return err == nil && required.Cmp(bid.Hold) <= 0
+}
+
+// tradeCost returns the quote amount the buyer of a trade pays the seller
+func tradeCost(trade *models.Trade) models.Decimal {
+	return trade.Price.MulRound(trade.Quantity, holdScale, models.RoundDown)
+}
+
+// setHold changes the funds held for an order, posting the difference as a
+// hold or release journal
+func (ob *OrderBook) setHold(order *models.Order, amount models.Decimal, tx *sql.Tx) error {
+	if err := ob.postHold(order, amount.Sub(order.Hold), tx); err != nil {
+		return err
+	}
+	order.Hold = amount
+	return nil
+}
+
+// postHold posts a journal moving delta of the hold asset of an order from the
+// available to the held balance of its account, or back when delta is negative
+func (ob *OrderBook) postHold(order *models.Order, delta models.Decimal, tx *sql.Tx) error {
+	if delta.IsZero() {
+		return nil
+	}
+	asset, err := ob.holdAsset(order)
+	if err != nil {
+		return err
+	}
+	journal := &models.Journal{
+		Type:        models.JournalTypeHold,
+		ReferenceID: order.ID,
+		Entries: []models.LedgerEntry{
+			{AccountID: order.AccountID, Asset: asset, Bucket: models.BucketAvailable, Amount: delta.Neg()},
+			{AccountID: order.AccountID, Asset: asset, Bucket: models.BucketHeld, Amount: delta},
+		},
+	}
+	if delta.Sign() < 0 {
+		journal.Type = models.JournalTypeRelease
+	}
+	if err := db.PostJournalTx(journal, tx); err != nil {
+		if err == db.ErrInsufficientFunds {
+			return reject(RejectInsufficientFunds, "insufficient funds: %s %s required", delta, asset)
+		}
+		log.Printf("Failed to post %s journal for order %d: %v", journal.Type, order.ID, err)
+		return err
+	}
+	return nil
+}
+
+// releaseHold returns part of an order's hold to its account
+func (ob *OrderBook) releaseHold(order *models.Order, amount models.Decimal, tx *sql.Tx) error {
+	return ob.setHold(order, order.Hold.Sub(amount), tx)
+}
+
+// settleTrade posts the settlement journal of a trade: the buyer pays price ×
+// quantity from its hold and gets the unused part of that portion back, the
+// seller delivers the quantity from its hold and receives the proceeds. Each side's
+// fee is taken from the asset it receives and credited to the fee account. It
+// must be called before the remaining quantities are reduced.
+func (ob *OrderBook) settleTrade(trade *models.Trade, bid, ask *models.Order, tx *sql.Tx) error {
+	base, quote, err := ob.assetsOf(trade.Symbol)
+	if err != nil {
+		return err
+	}
+	cost := tradeCost(trade)
+	paid := holdPortion(bid, trade.Quantity)
+	delivered := holdPortion(ask, trade.Quantity)
+
+	journal := &models.Journal{
+		Type:        models.JournalTypeTrade,
+		ReferenceID: int64(trade.ID),
+		Entries: []models.LedgerEntry{
+			// Quote asset: buyer pays from its hold, seller is credited
+			{AccountID: bid.AccountID, Asset: quote, Bucket: models.BucketHeld, Amount: paid.Neg()},
+			{AccountID: bid.AccountID, Asset: quote, Bucket: models.BucketAvailable, Amount: paid.Sub(cost)},
+			{AccountID: ask.AccountID, Asset: quote, Bucket: models.BucketAvailable, Amount: cost},
+			// Base asset: seller delivers from its hold, buyer is credited
+			{AccountID: ask.AccountID, Asset: base, Bucket: models.BucketHeld, Amount: delivered.Neg()},
+			{AccountID: ask.AccountID, Asset: base, Bucket: models.BucketAvailable, Amount: delivered.Sub(trade.Quantity)},
+			{AccountID: bid.AccountID, Asset: base, Bucket: models.BucketAvailable, Amount: trade.Quantity},
+			// Fees: negative amounts are maker rebates paid by the fee account
+			{AccountID: bid.AccountID, Asset: base, Bucket: models.BucketAvailable, Amount: trade.BuyFee.Neg()},
+			{AccountID: models.FeeAccountID, Asset: base, Bucket: models.BucketAvailable, Amount: trade.BuyFee},
+			{AccountID: ask.AccountID, Asset: quote, Bucket: models.BucketAvailable, Amount: trade.SellFee.Neg()},
+			{AccountID: models.FeeAccountID, Asset: quote, Bucket: models.BucketAvailable, Amount: trade.SellFee},
+		},
+		CreatedAt: trade.CreatedAt,
+	}
+	if err := db.PostJournalTx(journal, tx); err != nil {
+		log.Printf("Failed to settle trade %d between orders %d and %d: %v", trade.ID, bid.ID, ask.ID, err)
+		return err
+	}
+	bid.Hold = bid.Hold.Sub(paid)
+	ask.Hold = ask.Hold.Sub(delivered)
+	return nil
+}
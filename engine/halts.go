@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"golang-order-matching-system/db"
+	"golang-order-matching-system/models"
+)
+
+// RejectSymbolHalted is returned for orders that cannot be queued while their symbol is halted
+const RejectSymbolHalted = "SYMBOL_HALTED"
+
+// Errors returned for halt and resume commands
+var (
+	ErrSymbolHalted    = errors.New("symbol is already halted")
+	ErrSymbolNotHalted = errors.New("symbol is not halted")
+)
+
+// pricePoint is a trade price and the time it traded
+type pricePoint struct {
+	at    time.Time
+	price models.Decimal
+}
+
+// priceWindow keeps the trade prices of the circuit breaker's rolling window
+type priceWindow struct {
+	anchor *models.Decimal // last price before the window, nil when unknown
+	points []pricePoint
+}
+
+// reference drops the prices that traded before since and returns the price the
+// window starts from: the last price before it, or its first price
+func (w *priceWindow) reference(since time.Time) *models.Decimal {
+	for len(w.points) > 0 && w.points[0].at.Before(since) {
+		price := w.points[0].price
+		w.anchor = &price
+		w.points = w.points[1:]
+	}
+	if w.anchor != nil {
+		return w.anchor
+	}
+	if len(w.points) > 0 {
+		return &w.points[0].price
+	}
+	return nil
+}
+
+// restart empties the window, which then starts from price
+func (w *priceWindow) restart(price *models.Decimal) {
+	w.anchor = price
+	w.points = nil
+}
+
+// recordPrice adds a trade price to the circuit breaker window of a book
+func (ob *OrderBook) recordPrice(book *symbolBook, price models.Decimal) {
+	inst, ok := ob.Instruments.Get(book.symbol)
+	if !ok || inst.CircuitBreakerPct.IsZero() {
+		book.window.restart(&price)
+		return
+	}
+	book.window.points = append(book.window.points, pricePoint{at: time.Now(), price: price})
+}
+
+// checkCircuitBreaker halts the symbol of a book if trading at price would move it
+// more than the circuit breaker percentage from the start of the rolling window,
+// and reports whether it did
+func (ob *OrderBook) checkCircuitBreaker(book *symbolBook, price models.Decimal, tx *sql.Tx) (bool, error) {
+	inst, ok := ob.Instruments.Get(book.symbol)
+	if !ok || inst.CircuitBreakerPct.IsZero() {
+		return false, nil
+	}
+	window := time.Duration(inst.CircuitBreakerWindow) * time.Second
+	reference := book.window.reference(time.Now().Add(-window))
+	if reference == nil {
+		return false, nil
+	}
+	limit := reference.MulQuo(inst.CircuitBreakerPct, models.DecimalFromInt(100), holdScale)
+	if price.Sub(*reference).Abs().Cmp(limit) <= 0 {
+		return false, nil
+	}
+
+	ref, trigger := *reference, price
+	halt := &models.Halt{
+		Symbol:         book.symbol,
+		Reason:         models.HaltReasonCircuitBreaker,
+		Message:        fmt.Sprintf("a trade at %s would move the price more than %s%% from %s within %s", price, inst.CircuitBreakerPct, ref, window),
+		ReferencePrice: &ref,
+		TriggerPrice:   &trigger,
+	}
+	return true, ob.haltTx(book, halt, tx)
+}
+
+// haltTx stores a halt and stops matching in its book
+func (ob *OrderBook) haltTx(book *symbolBook, halt *models.Halt, tx *sql.Tx) error {
+	halt.HaltedAt = time.Now()
+	if err := db.CreateHaltTx(halt, tx); err != nil {
+		return err
+	}
+	book.halt = halt
+	log.Printf("Trading in %s halted (%s): %s", book.symbol, halt.Reason, halt.Message)
+	return nil
+}
+
+// saveHalt stores a halt in its own transaction and stops matching in its book
+func (ob *OrderBook) saveHalt(book *symbolBook, halt *models.Halt) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Printf("Failed to begin transaction: %v", err)
+		return err
+	}
+	if err := ob.haltTx(book, halt, tx); err != nil {
+		tx.Rollback()
+		book.halt = nil
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		log.Printf("Failed to commit halt of %s: %v", book.symbol, err)
+		book.halt = nil
+		return err
+	}
+	return nil
+}
+
+// processHalt halts a symbol at the request of an operator
+func (ob *OrderBook) processHalt(book *symbolBook, message string) (*models.Halt, error) {
+	if book.halt != nil {
+		return nil, ErrSymbolHalted
+	}
+	halt := &models.Halt{Symbol: book.symbol, Reason: models.HaltReasonManual, Message: message}
+	if err := ob.saveHalt(book, halt); err != nil {
+		return nil, err
+	}
+	return halt, nil
+}
+
+// processResume ends the halt of a symbol with a reopening auction: the orders
+// queued during the halt uncross at a single price, then continuous trading and
+// stop triggering resume from that price. A symbol that is outside its continuous
+// phase keeps accumulating orders for the auction of its session instead.
+func (ob *OrderBook) processResume(book *symbolBook) (halt *models.Halt, err error) {
+	if book.halt == nil {
+		return nil, ErrSymbolNotHalted
+	}
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Printf("Failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			ob.reload(book)
+			log.Printf("Transaction rolled back for reopening of %s due to error: %v", book.symbol, err)
+		}
+	}()
+
+	halt = book.halt
+	if book.phase == models.PhaseContinuous {
+		auction := indicativeAuction(book, book.lastPrice)
+		if err = ob.uncross(book, auction, tx); err != nil {
+			return nil, err
+		}
+		if auction.Price != nil {
+			book.window.restart(auction.Price)
+		}
+		halt.Reopening = auction
+	}
+
+	resumedAt := time.Now()
+	halt.ResumedAt = &resumedAt
+	if err = db.ResumeHaltTx(halt, tx); err != nil {
+		return nil, err
+	}
+	book.halt = nil
+
+	if err = ob.processTriggers(book, tx); err != nil {
+		return nil, err
+	}
+	if err = ob.commit(book, tx); err != nil {
+		log.Printf("Failed to commit reopening of %s: %v", book.symbol, err)
+		return nil, err
+	}
+	if halt.Reopening != nil {
+		log.Printf("Trading in %s resumed after reopening auction at %v", book.symbol, halt.Reopening.Price)
+	} else {
+		log.Printf("Halt of %s lifted during its %s phase", book.symbol, book.phase)
+	}
+	return halt, nil
+}
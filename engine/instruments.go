@@ -0,0 +1,308 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"golang-order-matching-system/db"
+	"golang-order-matching-system/models"
+)
+
+// Reject codes returned when an order breaks the trading rules of its instrument
+const (
+	RejectUnknownSymbol       = "UNKNOWN_SYMBOL"
+	RejectInstrumentNotActive = "INSTRUMENT_NOT_TRADING"
+	RejectPricePrecision      = "PRICE_PRECISION_EXCEEDED"
+	RejectPriceOffTick        = "PRICE_NOT_MULTIPLE_OF_TICK"
+	RejectQuantityOffLot      = "QUANTITY_NOT_MULTIPLE_OF_LOT"
+	RejectQuantityTooSmall    = "QUANTITY_BELOW_MINIMUM"
+	RejectQuantityTooLarge    = "QUANTITY_ABOVE_MAXIMUM"
+	RejectNotionalTooSmall    = "NOTIONAL_BELOW_MINIMUM"
+	RejectNotionalTooLarge    = "NOTIONAL_ABOVE_MAXIMUM"
+	RejectQuantityPrecision   = "QUANTITY_PRECISION_EXCEEDED"
+)
+
+// RejectError is returned when an order is refused, with a machine-readable code
+type RejectError struct {
+	Code    string
+	Message string
+}
+
+func (e *RejectError) Error() string {
+	return e.Message
+}
+
+func reject(code, format string, args ...interface{}) *RejectError {
+	return &RejectError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// DefaultMarketCollarPct is the market collar of instruments that do not set one
+var DefaultMarketCollarPct = models.DecimalFromInt(10)
+
+// DefaultCircuitBreakerWindow is the circuit breaker window in seconds of instruments that do not set one
+const DefaultCircuitBreakerWindow = 300
+
+// Errors returned by the instrument registry
+var (
+	ErrInstrumentExists   = errors.New("instrument already exists")
+	ErrInstrumentNotFound = errors.New("instrument not found")
+	ErrInvalidInstrument  = errors.New("invalid instrument")
+)
+
+// InstrumentRegistry caches the instruments table and validates orders against it
+type InstrumentRegistry struct {
+	mu          sync.RWMutex
+	instruments map[string]models.Instrument
+}
+
+// NewInstrumentRegistry creates an empty instrument registry
+func NewInstrumentRegistry() *InstrumentRegistry {
+	return &InstrumentRegistry{
+		instruments: make(map[string]models.Instrument),
+	}
+}
+
+// Load reads all instruments from the database into the registry
+func (r *InstrumentRegistry) Load() error {
+	instruments, err := db.GetInstruments()
+	if err != nil {
+		log.Printf("Failed to load instruments: %v", err)
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.instruments = make(map[string]models.Instrument)
+	for _, inst := range instruments {
+		r.instruments[inst.Symbol] = inst
+	}
+	log.Printf("Instrument registry loaded with %d instruments", len(instruments))
+	return nil
+}
+
+// Get returns the instrument for a symbol
+func (r *InstrumentRegistry) Get(symbol string) (models.Instrument, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	inst, ok := r.instruments[symbol]
+	return inst, ok
+}
+
+// List returns all instruments sorted by symbol
+func (r *InstrumentRegistry) List() []models.Instrument {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	instruments := make([]models.Instrument, 0, len(r.instruments))
+	for _, inst := range r.instruments {
+		instruments = append(instruments, inst)
+	}
+	sort.Slice(instruments, func(i, j int) bool {
+		return instruments[i].Symbol < instruments[j].Symbol
+	})
+	return instruments
+}
+
+// Create validates and stores a new instrument
+func (r *InstrumentRegistry) Create(inst *models.Instrument) error {
+	if err := validateInstrument(inst); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.instruments[inst.Symbol]; ok {
+		return ErrInstrumentExists
+	}
+	inst.CreatedAt = time.Now()
+	inst.UpdatedAt = inst.CreatedAt
+	if err := db.CreateInstrument(inst); err != nil {
+		return err
+	}
+	r.instruments[inst.Symbol] = *inst
+	return nil
+}
+
+// Update validates and replaces the trading rules of an existing instrument
+func (r *InstrumentRegistry) Update(inst *models.Instrument) error {
+	if err := validateInstrument(inst); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.instruments[inst.Symbol]
+	if !ok {
+		return ErrInstrumentNotFound
+	}
+	inst.CreatedAt = current.CreatedAt
+	inst.UpdatedAt = time.Now()
+	if err := db.UpdateInstrument(inst); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrInstrumentNotFound
+		}
+		return err
+	}
+	r.instruments[inst.Symbol] = *inst
+	return nil
+}
+
+// Delete removes an instrument
+func (r *InstrumentRegistry) Delete(symbol string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.instruments[symbol]; !ok {
+		return ErrInstrumentNotFound
+	}
+	if err := db.DeleteInstrument(symbol); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	delete(r.instruments, symbol)
+	return nil
+}
+
+// validateInstrument checks that an instrument definition is usable
+func validateInstrument(inst *models.Instrument) error {
+	switch {
+	case inst.Symbol == "" || len(inst.Symbol) > 10:
+		return fmt.Errorf("%w: symbol is required and must be at most 10 characters", ErrInvalidInstrument)
+	case inst.BaseAsset == "" || inst.QuoteAsset == "":
+		return fmt.Errorf("%w: base_asset and quote_asset are required", ErrInvalidInstrument)
+	case inst.TickSize.Sign() <= 0:
+		return fmt.Errorf("%w: tick_size must be greater than 0", ErrInvalidInstrument)
+	case inst.LotSize.Sign() <= 0:
+		return fmt.Errorf("%w: lot_size must be greater than 0", ErrInvalidInstrument)
+	case inst.PricePrecision < 0 || inst.PricePrecision > 8:
+		return fmt.Errorf("%w: price_precision must be between 0 and 8", ErrInvalidInstrument)
+	case inst.TickSize.Scale() > inst.PricePrecision && !inst.TickSize.Rescale(inst.PricePrecision).Equal(inst.TickSize):
+		return fmt.Errorf("%w: tick_size has more decimal places than price_precision", ErrInvalidInstrument)
+	case inst.MinQuantity.Sign() < 0 || inst.MaxQuantity.Sign() < 0:
+		return fmt.Errorf("%w: min_quantity and max_quantity must not be negative", ErrInvalidInstrument)
+	case inst.MaxQuantity.Sign() > 0 && inst.MaxQuantity.Cmp(inst.MinQuantity) < 0:
+		return fmt.Errorf("%w: max_quantity must not be below min_quantity", ErrInvalidInstrument)
+	case inst.MinNotional.Sign() < 0 || inst.MaxNotional.Sign() < 0:
+		return fmt.Errorf("%w: min_notional and max_notional must not be negative", ErrInvalidInstrument)
+	case inst.MaxNotional.Sign() > 0 && inst.MaxNotional.Cmp(inst.MinNotional) < 0:
+		return fmt.Errorf("%w: max_notional must not be below min_notional", ErrInvalidInstrument)
+	case inst.MarketCollarPct.Sign() < 0 || inst.MarketCollarPct.Cmp(models.DecimalFromInt(100)) > 0:
+		return fmt.Errorf("%w: market_collar_pct must be between 0 and 100", ErrInvalidInstrument)
+	case inst.CircuitBreakerPct.Sign() < 0 || inst.CircuitBreakerWindow < 0:
+		return fmt.Errorf("%w: circuit_breaker_pct and circuit_breaker_window must not be negative", ErrInvalidInstrument)
+	case inst.TopOrderMax.Sign() < 0:
+		return fmt.Errorf("%w: top_order_max must not be negative", ErrInvalidInstrument)
+	case inst.LMMPct.Sign() < 0 || inst.LMMPct.Cmp(models.DecimalFromInt(100)) > 0:
+		return fmt.Errorf("%w: lmm_pct must be between 0 and 100", ErrInvalidInstrument)
+	case inst.MatchingAlgorithm == models.MatchingLMM && (inst.LMMAccountID <= 0 || inst.LMMPct.IsZero()):
+		return fmt.Errorf("%w: the lmm algorithm requires lmm_account_id and lmm_pct", ErrInvalidInstrument)
+	}
+	if inst.MatchingAlgorithm == "" {
+		inst.MatchingAlgorithm = models.MatchingFIFO
+	}
+	if _, ok := matchingAlgorithms[inst.MatchingAlgorithm]; !ok {
+		return fmt.Errorf("%w: unknown matching_algorithm %q", ErrInvalidInstrument, inst.MatchingAlgorithm)
+	}
+	if inst.MarketCollarPct.IsZero() {
+		inst.MarketCollarPct = DefaultMarketCollarPct
+	}
+	if inst.CircuitBreakerWindow == 0 {
+		inst.CircuitBreakerWindow = DefaultCircuitBreakerWindow
+	}
+	switch inst.Status {
+	case "":
+		inst.Status = models.InstrumentStatusTrading
+	case models.InstrumentStatusTrading, models.InstrumentStatusHalted, models.InstrumentStatusClosed:
+	default:
+		return fmt.Errorf("%w: status must be one of trading, halted, closed", ErrInvalidInstrument)
+	}
+	return nil
+}
+
+// ValidateOrder checks an order against the trading rules of its instrument and
+// rescales its prices to the instrument's price precision and its quantities to
+// the scale of its lot size, so values written with extra digits cannot inflate
+// the scale of the engine's arithmetic. Violations are reported as *RejectError.
+func (r *InstrumentRegistry) ValidateOrder(order *models.Order) error {
+	inst, ok := r.Get(order.Symbol)
+	if !ok {
+		return reject(RejectUnknownSymbol, "Unknown symbol: %s", order.Symbol)
+	}
+	if inst.Status != models.InstrumentStatusTrading {
+		return reject(RejectInstrumentNotActive, "Instrument %s is not trading, status is %s", inst.Symbol, inst.Status)
+	}
+
+	if order.Price != nil {
+		price, ok := fitScale(*order.Price, inst.PricePrecision)
+		if !ok {
+			return reject(RejectPricePrecision, "Price %s has more than %d decimal places", order.Price, inst.PricePrecision)
+		}
+		if !price.Mod(inst.TickSize).IsZero() {
+			return reject(RejectPriceOffTick, "Price %s is not a multiple of tick size %s", price, inst.TickSize)
+		}
+		order.Price = &price
+	}
+	if order.StopPrice != nil {
+		stopPrice, ok := fitScale(*order.StopPrice, inst.PricePrecision)
+		if !ok {
+			return reject(RejectPricePrecision, "Stop price %s has more than %d decimal places", order.StopPrice, inst.PricePrecision)
+		}
+		if !stopPrice.Mod(inst.TickSize).IsZero() {
+			return reject(RejectPriceOffTick, "Stop price %s is not a multiple of tick size %s", stopPrice, inst.TickSize)
+		}
+		order.StopPrice = &stopPrice
+	}
+
+	quantity, ok := fitScale(order.Quantity, inst.LotSize.Scale())
+	if !ok {
+		return reject(RejectQuantityPrecision, "Quantity %s has more than %d decimal places", order.Quantity, inst.LotSize.Scale())
+	}
+	if !quantity.Mod(inst.LotSize).IsZero() {
+		return reject(RejectQuantityOffLot, "Quantity %s is not a multiple of lot size %s", quantity, inst.LotSize)
+	}
+	order.Quantity = quantity
+	if order.DisplayQuantity != nil {
+		display, ok := fitScale(*order.DisplayQuantity, inst.LotSize.Scale())
+		if !ok {
+			return reject(RejectQuantityPrecision, "Display quantity %s has more than %d decimal places", order.DisplayQuantity, inst.LotSize.Scale())
+		}
+		if !display.Mod(inst.LotSize).IsZero() {
+			return reject(RejectQuantityOffLot, "Display quantity %s is not a multiple of lot size %s", display, inst.LotSize)
+		}
+		order.DisplayQuantity = &display
+	}
+	if order.Quantity.Cmp(inst.MinQuantity) < 0 {
+		return reject(RejectQuantityTooSmall, "Quantity %s is below the minimum of %s", order.Quantity, inst.MinQuantity)
+	}
+	if inst.MaxQuantity.Sign() > 0 && order.Quantity.Cmp(inst.MaxQuantity) > 0 {
+		return reject(RejectQuantityTooLarge, "Quantity %s is above the maximum of %s", order.Quantity, inst.MaxQuantity)
+	}
+
+	if order.Price != nil {
+		// Rounding the notional toward each bound keeps both checks exact, as the
+		// bounds themselves have at most holdScale decimal places
+		low, errLow := order.Price.CheckedMulRound(order.Quantity, holdScale, models.RoundDown)
+		high, errHigh := order.Price.CheckedMulRound(order.Quantity, holdScale, models.RoundUp)
+		if errLow != nil || errHigh != nil {
+			return reject(RejectNotionalTooLarge, "Notional of %s at %s is out of range", order.Quantity, order.Price)
+		}
+		if low.Cmp(inst.MinNotional) < 0 {
+			return reject(RejectNotionalTooSmall, "Notional %s is below the minimum of %s", low, inst.MinNotional)
+		}
+		if inst.MaxNotional.Sign() > 0 && high.Cmp(inst.MaxNotional) > 0 {
+			return reject(RejectNotionalTooLarge, "Notional %s is above the maximum of %s", high, inst.MaxNotional)
+		}
+	}
+	return nil
+}
+
+// fitScale returns value at scale, or false if that drops nonzero digits or does not fit
+func fitScale(value models.Decimal, scale int32) (models.Decimal, bool) {
+	rescaled, err := value.CheckedRescale(scale)
+	if err != nil || !rescaled.Equal(value) {
+		return models.Decimal{}, false
+	}
+	return rescaled, true
+}
@@ -0,0 +1,417 @@
+package engine
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"golang-order-matching-system/models"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before it is dropped
+const subscriberBuffer = 1024
+
+// ErrUnknownChannel is returned for subscriptions to a channel that does not exist
+var ErrUnknownChannel = errors.New("unknown market data channel")
+
+// Subscriber receives the market data events of the symbols and channels it
+// subscribed to. Sequencers never wait for a subscriber: one whose buffer is full
+// is dropped and its Done channel closed.
+type Subscriber struct {
+	events  chan models.MarketEvent
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Bool
+}
+
+// NewSubscriber creates a subscriber without subscriptions
+func NewSubscriber() *Subscriber {
+	return &Subscriber{
+		events: make(chan models.MarketEvent, subscriberBuffer),
+		done:   make(chan struct{}),
+	}
+}
+
+// Events returns the channel events are delivered on
+func (s *Subscriber) Events() <-chan models.MarketEvent {
+	return s.events
+}
+
+// Done is closed once the subscriber is closed or dropped
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Dropped reports whether the subscriber was dropped for falling behind
+func (s *Subscriber) Dropped() bool {
+	return s.dropped.Load()
+}
+
+// Close ends all subscriptions; books forget the subscriber on their next publication
+func (s *Subscriber) Close() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// closed reports whether the subscriber was closed or dropped
+func (s *Subscriber) closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// send queues an event without blocking and drops the subscriber when its buffer is full
+func (s *Subscriber) send(event models.MarketEvent) {
+	if s.closed() {
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+		s.dropped.Store(true)
+		s.Close()
+		log.Printf("Market data subscriber dropped, %d events behind on %s", len(s.events), event.Symbol)
+	}
+}
+
+// depthState is the aggregated depth of a book as last published to its subscribers
+type depthState struct {
+	bids []models.PriceLevel
+	asks []models.PriceLevel
+	seq  int64 // engine sequence number of the last depth update
+}
+
+// validChannel reports whether channel is a market data channel
+func validChannel(channel string) bool {
+	switch channel {
+	case models.ChannelDepth, models.ChannelTrades, models.ChannelBBO:
+		return true
+	}
+	return false
+}
+
+// depthLevels aggregates the visible quantity of a side by price level, best first.
+// Resting market orders have no price and are left out.
+func depthLevels(side *bookSide) []models.PriceLevel {
+	levels := make([]models.PriceLevel, 0, len(side.levels))
+	for _, lvl := range side.levels {
+		level := models.PriceLevel{Price: *lvl.price}
+		for e := lvl.orders.Front(); e != nil; e = e.Next() {
+			level.Quantity = level.Quantity.Add(visibleQuantity(e.Value.(*bookEntry).order))
+			level.Orders++
+		}
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+// bookLevels lists the resting orders of a side level by level in priority order:
+// resting market orders first, then the price levels best first
+func bookLevels(side *bookSide) []models.BookLevel {
+	levels := make([]models.BookLevel, 0, len(side.levels)+1)
+	for _, lvl := range append([]*priceLevel{side.market}, side.levels...) {
+		if lvl.orders.Len() == 0 {
+			continue
+		}
+		level := models.BookLevel{Orders: make([]models.BookOrder, 0, lvl.orders.Len())}
+		if lvl.price != nil {
+			price := *lvl.price
+			level.Price = &price
+		}
+		for e := lvl.orders.Front(); e != nil; e = e.Next() {
+			order := e.Value.(*bookEntry).order
+			quantity := visibleQuantity(order)
+			level.Quantity = level.Quantity.Add(quantity)
+			level.Orders = append(level.Orders, models.BookOrder{
+				OrderID:  order.ID,
+				Position: len(level.Orders) + 1,
+				Quantity: quantity,
+				QueuedAt: order.QueuedAt,
+				Top:      order == side.top,
+			})
+		}
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+// publicOrders returns copies of the resting orders of a side in priority order,
+// at most limit of them unless it is zero, without their owners. Iceberg orders
+// only show their current slice.
+func publicOrders(side *bookSide, limit int) []models.Order {
+	var orders []models.Order
+	for _, resting := range side.orderList() {
+		if limit > 0 && len(orders) == limit {
+			break
+		}
+		order := *resting
+		order.AccountID = 0
+		order.STPMode = ""
+		if order.DisplayQuantity != nil {
+			order.Quantity = *order.DisplayQuantity
+			order.RemainingQuantity = order.VisibleQuantity
+			order.DisplayQuantity = nil
+			order.VisibleQuantity = models.Decimal{}
+		}
+		orders = append(orders, order)
+	}
+	return orders
+}
+
+// groupLevels merges the levels of a side into price buckets of size group, bid
+// prices rounded down and ask prices up so grouped sides never cross, and keeps
+// the best depth buckets. A zero group only applies the depth.
+func groupLevels(levels []models.PriceLevel, side string, group models.Decimal, depth int) []models.PriceLevel {
+	grouped := make([]models.PriceLevel, 0, min(len(levels), depth))
+	for _, level := range levels {
+		price := level.Price
+		if group.Sign() > 0 {
+			remainder := price.Mod(group)
+			price = price.Sub(remainder)
+			if side == "sell" && !remainder.IsZero() {
+				price = price.Add(group)
+			}
+		}
+		// Levels are sorted best first, so a bucket only continues the last one
+		if n := len(grouped); n > 0 && grouped[n-1].Price.Equal(price) {
+			grouped[n-1].Quantity = grouped[n-1].Quantity.Add(level.Quantity)
+			grouped[n-1].Orders += level.Orders
+			continue
+		}
+		if len(grouped) == depth {
+			break
+		}
+		grouped = append(grouped, models.PriceLevel{Price: price, Quantity: level.Quantity, Orders: level.Orders})
+	}
+	return grouped
+}
+
+// diffLevels returns the levels of current that differ from previous, best first,
+// followed by the levels that are gone with a zero quantity
+func diffLevels(previous, current []models.PriceLevel) []models.PriceLevel {
+	before := make(map[string]models.PriceLevel, len(previous))
+	for _, level := range previous {
+		before[level.Price.String()] = level
+	}
+	var changes []models.PriceLevel
+	for _, level := range current {
+		key := level.Price.String()
+		if old, ok := before[key]; !ok || !old.Quantity.Equal(level.Quantity) || old.Orders != level.Orders {
+			changes = append(changes, level)
+		}
+		delete(before, key)
+	}
+	for _, level := range previous {
+		if _, gone := before[level.Price.String()]; gone {
+			changes = append(changes, models.PriceLevel{Price: level.Price})
+		}
+	}
+	return changes
+}
+
+// topOf returns the best level of a side, or nil if it is empty
+func topOf(levels []models.PriceLevel) *models.PriceLevel {
+	if len(levels) == 0 {
+		return nil
+	}
+	top := levels[0]
+	return &top
+}
+
+// sameLevel reports whether two optional levels are equal
+func sameLevel(a, b *models.PriceLevel) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Price.Equal(b.Price) && a.Quantity.Equal(b.Quantity) && a.Orders == b.Orders
+}
+
+// PublicTrade returns a trade as published on the market data feeds, without accounts or fees
+func PublicTrade(trade *models.Trade) *models.Trade {
+	return &models.Trade{
+		ID:          trade.ID,
+		Symbol:      trade.Symbol,
+		BuyOrderID:  trade.BuyOrderID,
+		SellOrderID: trade.SellOrderID,
+		Price:       trade.Price,
+		Quantity:    trade.Quantity,
+		TakerSide:   trade.TakerSide,
+		CreatedAt:   trade.CreatedAt,
+	}
+}
+
+// watching reports whether any live subscriber of a book follows one of the channels
+func (b *symbolBook) watching(channels ...string) bool {
+	for sub, subscribed := range b.subscribers {
+		if sub.closed() {
+			continue
+		}
+		for _, channel := range channels {
+			if subscribed[channel] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// broadcast sends an event to the live subscribers of a channel and forgets closed subscribers
+func (b *symbolBook) broadcast(channel string, event models.MarketEvent) {
+	for sub, subscribed := range b.subscribers {
+		if sub.closed() {
+			delete(b.subscribers, sub)
+			continue
+		}
+		if subscribed[channel] {
+			sub.send(event)
+		}
+	}
+}
+
+// bboEvent returns the best bid and offer of the published depth
+func (b *symbolBook) bboEvent() models.MarketEvent {
+	return models.MarketEvent{
+		Type:   models.EventBBO,
+		Symbol: b.symbol,
+		Seq:    b.seq,
+		Bid:    topOf(b.depth.bids),
+		Ask:    topOf(b.depth.asks),
+	}
+}
+
+// publishMarketData sends the trades committed by the last command and the depth
+// and best bid and offer changes it made to the subscribers of a book. The depth
+// is only tracked while someone watches it.
+func (b *symbolBook) publishMarketData(trades []*models.Trade) {
+	for _, trade := range trades {
+		b.broadcast(models.ChannelTrades, models.MarketEvent{
+			Type:   models.EventTrade,
+			Symbol: b.symbol,
+			Seq:    b.seq,
+			Trade:  PublicTrade(trade),
+		})
+	}
+	if !b.watching(models.ChannelDepth, models.ChannelBBO) {
+		return
+	}
+
+	bids, asks := depthLevels(b.bids), depthLevels(b.asks)
+	bidChanges := diffLevels(b.depth.bids, bids)
+	askChanges := diffLevels(b.depth.asks, asks)
+	if len(bidChanges) == 0 && len(askChanges) == 0 {
+		return
+	}
+	topChanged := !sameLevel(topOf(b.depth.bids), topOf(bids)) || !sameLevel(topOf(b.depth.asks), topOf(asks))
+
+	b.broadcast(models.ChannelDepth, models.MarketEvent{
+		Type:    models.EventDepthUpdate,
+		Symbol:  b.symbol,
+		Seq:     b.seq,
+		PrevSeq: b.depth.seq,
+		Bids:    bidChanges,
+		Asks:    askChanges,
+	})
+	b.depth = depthState{bids: bids, asks: asks, seq: b.seq}
+	if topChanged {
+		b.broadcast(models.ChannelBBO, b.bboEvent())
+	}
+}
+
+// subscribe registers a subscriber for a channel of a book. Depth subscribers get
+// a snapshot and bbo subscribers the current best bid and offer first. The depth
+// is refreshed if nobody watched it, so the next update chains to it.
+func (b *symbolBook) subscribe(sub *Subscriber, channel string) {
+	if (channel == models.ChannelDepth || channel == models.ChannelBBO) && !b.watching(models.ChannelDepth, models.ChannelBBO) {
+		b.depth = depthState{bids: depthLevels(b.bids), asks: depthLevels(b.asks), seq: b.seq}
+	}
+	if b.subscribers[sub] == nil {
+		b.subscribers[sub] = make(map[string]bool)
+	}
+	b.subscribers[sub][channel] = true
+
+	switch channel {
+	case models.ChannelDepth:
+		sub.send(models.MarketEvent{
+			Type:   models.EventDepthSnapshot,
+			Symbol: b.symbol,
+			Seq:    b.seq,
+			Bids:   b.depth.bids,
+			Asks:   b.depth.asks,
+		})
+	case models.ChannelBBO:
+		sub.send(b.bboEvent())
+	}
+}
+
+// unsubscribe removes a subscriber from a channel of a book
+func (b *symbolBook) unsubscribe(sub *Subscriber, channel string) {
+	if subscribed, ok := b.subscribers[sub]; ok {
+		delete(subscribed, channel)
+		if len(subscribed) == 0 {
+			delete(b.subscribers, sub)
+		}
+	}
+}
+
+// Subscribe registers a subscriber for a market data channel of a symbol. The
+// depth snapshot or current best bid and offer is taken by the symbol's sequencer
+// together with the registration, so no update is missed or repeated.
+func (ob *OrderBook) Subscribe(sub *Subscriber, symbol, channel string) error {
+	if !validChannel(channel) {
+		return ErrUnknownChannel
+	}
+	ob.submit(symbol, command{kind: cmdQuery, query: func(book *symbolBook) {
+		book.subscribe(sub, channel)
+	}})
+	return nil
+}
+
+// Depth returns the aggregated visible depth of a symbol, at most depth levels per
+// side, grouped into price buckets of size group unless it is zero. It is taken by
+// the symbol's sequencer together with the engine sequence number.
+func (ob *OrderBook) Depth(symbol string, depth int, group models.Decimal) models.DepthSnapshot {
+	snapshot := models.DepthSnapshot{Symbol: symbol}
+	if group.Sign() > 0 {
+		snapshot.Group = &group
+	}
+	ob.submit(symbol, command{kind: cmdQuery, query: func(book *symbolBook) {
+		snapshot.Seq = book.seq
+		snapshot.Bids = groupLevels(depthLevels(book.bids), "buy", group, depth)
+		snapshot.Asks = groupLevels(depthLevels(book.asks), "sell", group, depth)
+	}})
+	return snapshot
+}
+
+// Snapshot returns every resting order of a symbol with its queue position, taken
+// by the symbol's sequencer between commands together with the engine sequence
+// number, so it lines up with the market data feed
+func (ob *OrderBook) Snapshot(symbol string) models.BookSnapshot {
+	snapshot := models.BookSnapshot{Symbol: symbol}
+	ob.submit(symbol, command{kind: cmdQuery, query: func(book *symbolBook) {
+		snapshot.Seq = book.seq
+		snapshot.Bids = bookLevels(book.bids)
+		snapshot.Asks = bookLevels(book.asks)
+	}})
+	return snapshot
+}
+
+// RestingOrders returns the visible resting orders of a symbol per side in
+// priority order, at most limit per side unless it is zero. Like Depth it is
+// taken by the symbol's sequencer between commands.
+func (ob *OrderBook) RestingOrders(symbol string, limit int) (bids, asks []models.Order) {
+	ob.submit(symbol, command{kind: cmdQuery, query: func(book *symbolBook) {
+		bids = publicOrders(book.bids, limit)
+		asks = publicOrders(book.asks, limit)
+	}})
+	return bids, asks
+}
+
+// Unsubscribe removes a subscriber from a market data channel of a symbol
+func (ob *OrderBook) Unsubscribe(sub *Subscriber, symbol, channel string) {
+	ob.submit(symbol, command{kind: cmdQuery, query: func(book *symbolBook) {
+		book.unsubscribe(sub, channel)
+	}})
+}
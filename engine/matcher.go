@@ -2,9 +2,10 @@ package engine
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"sort"
+	"sync"
 	"time"
 
 	"golang-order-matching-system/db"
@@ -20,242 +21,1024 @@ const (
 	OrderStatusCanceled       = "canceled"
 )
 
-// OrderBook manages the in-memory order book for matching
+// TimeInForce constants
+const (
+	TimeInForceGTC = "GTC" // good till canceled: the remainder rests in the book
+	TimeInForceIOC = "IOC" // immediate or cancel: the remainder is canceled after matching
+	TimeInForceFOK = "FOK" // fill or kill: fills completely or is rejected without trades
+)
+
+// Reject codes for orders refused by the matching engine
+const (
+	RejectFOKNotFillable     = "FOK_NOT_FILLABLE"
+	RejectPostOnlyWouldCross = "POST_ONLY_WOULD_CROSS"
+	RejectInsufficientFunds  = "INSUFFICIENT_FUNDS"
+	RejectValueOutOfRange    = "VALUE_OUT_OF_RANGE"
+)
+
+// Post-only modes
+const (
+	PostOnlyReject  = "reject"  // refuse an order that would take liquidity
+	PostOnlyReprice = "reprice" // move the price one tick away from the opposite touch
+)
+
+// OrderEvent types recorded in the order_events table
+const (
+	OrderEventTriggered          = "stop_triggered"
+	OrderEventPostOnlyRepriced   = "post_only_repriced"
+	OrderEventSelfTradePrevented = "self_trade_prevented"
+	OrderEventMarketProtection   = "market_protection"
+)
+
+// Self-trade prevention modes, applied by the incoming order when it would
+// trade against a resting order of the same account
+const (
+	STPCancelNewest       = "cancel_newest"        // cancel the incoming order
+	STPCancelOldest       = "cancel_oldest"        // cancel the resting order and keep matching
+	STPCancelBoth         = "cancel_both"          // cancel both orders
+	STPDecrementAndCancel = "decrement_and_cancel" // reduce both by the smaller quantity, canceling whichever reaches zero
+)
+
+// Errors returned by the order book for commands it cannot apply
+var (
+	ErrOrderNotFound   = errors.New("order not found in order book")
+	ErrAmendNotAllowed = errors.New("only resting limit orders can be amended")
+	ErrAmendQuantity   = errors.New("amended quantity must exceed the filled quantity")
+)
+
+// OrderBook manages the in-memory order books used for matching. The books are
+// loaded from the database once and kept authoritative in memory afterwards;
+// the database only receives writes. Each symbol is owned by its own sequencer
+// goroutine, so all mutations of a book are serialized while different symbols
+// match in parallel.
 type OrderBook struct {
-	Orders map[string][]*models.Order
+	mu          sync.Mutex // guards books
+	books       map[string]*symbolBook
+	Instruments *InstrumentRegistry
+	Fees        *FeeSchedules
+	Risk        *RiskManager
+	Sessions    *SessionScheduler
+	Executions  *ExecutionHub
 }
 
 // NewOrderBook creates a new order book instance
 func NewOrderBook() *OrderBook {
-	return &OrderBook{
-		Orders: make(map[string][]*models.Order),
+	ob := &OrderBook{
+		books:       make(map[string]*symbolBook),
+		Instruments: NewInstrumentRegistry(),
+		Fees:        NewFeeSchedules(),
+		Risk:        NewRiskManager(),
+		Executions:  NewExecutionHub(),
 	}
+	ob.Sessions = NewSessionScheduler(ob)
+	return ob
 }
 
-// min returns the minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
+// Load reads the instrument registry, fee schedules, risk limits and session schedules, rebuilds the in-memory
+// books from the open orders stored in the database and starts a sequencer for every symbol found
+func (ob *OrderBook) Load() error {
+	if err := ob.Instruments.Load(); err != nil {
+		return err
+	}
+	if err := ob.Fees.Load(); err != nil {
+		return err
 	}
-	return b
+	if err := ob.Risk.Load(); err != nil {
+		return err
+	}
+	if err := ob.Sessions.Load(); err != nil {
+		return err
+	}
+
+	orders, err := db.GetOpenOrders("")
+	if err != nil {
+		log.Printf("Failed to load open orders: %v", err)
+		return err
+	}
+
+	bySymbol := make(map[string][]models.Order)
+	for _, order := range orders {
+		bySymbol[order.Symbol] = append(bySymbol[order.Symbol], order)
+	}
+
+	for _, inst := range ob.Instruments.List() {
+		if _, ok := bySymbol[inst.Symbol]; !ok {
+			bySymbol[inst.Symbol] = nil
+		}
+	}
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	for symbol, symbolOrders := range bySymbol {
+		if _, ok := ob.books[symbol]; ok {
+			continue
+		}
+		ob.startBook(symbol, symbolOrders)
+	}
+	log.Printf("Order book loaded with %d open orders across %d symbols", len(orders), len(ob.books))
+	return nil
 }
 
-// matchOrders performs the core matching logic between bids and asks within a transaction
-func (ob *OrderBook) matchOrders(bids, asks []*models.Order, tx *sql.Tx) bool {
-	matched := false
-	processedPairs := make(map[string]bool)
-
-	for len(bids) > 0 && len(asks) > 0 {
-		bid := bids[0]
-		ask := asks[0]
-
-		if bid.Type == "market" || ask.Type == "market" {
-			quantity := min(bid.RemainingQuantity, ask.RemainingQuantity)
-			if quantity > 0 {
-				bid.RemainingQuantity -= quantity
-				ask.RemainingQuantity -= quantity
-				updateOrderStatus(bid)
-				updateOrderStatus(ask)
-
-				if err := db.UpdateOrderTx(bid, tx); err != nil {
-					log.Printf("Failed to update bid order %d: %v", bid.ID, err)
-					return false
-				}
-				if err := db.UpdateOrderTx(ask, tx); err != nil {
-					log.Printf("Failed to update ask order %d: %v", ask.ID, err)
-					return false
-				}
+// reload replaces the contents of a book with the state committed in the database.
+// It is used after a failed transaction to discard in-memory changes.
+func (ob *OrderBook) reload(book *symbolBook) {
+	orders, err := db.GetOpenOrders(book.symbol)
+	if err != nil {
+		log.Printf("Failed to reload order book for %s: %v", book.symbol, err)
+		return
+	}
+	book.reset(orders)
+	restoreState(book)
+}
 
-				if err := logTrade(bid, ask, *ask.Price, quantity, tx); err != nil {
-					log.Printf("Failed to log trade for orders %d and %d: %v", bid.ID, ask.ID, err)
-					return false
-				}
+// restoreState reads the last trade price, any active halt and the trading phase
+// of a book from the database
+func restoreState(book *symbolBook) {
+	if price, err := db.GetLastTradePrice(book.symbol); err == nil {
+		book.lastPrice = price
+	}
+	book.window.restart(book.lastPrice)
+	if halt, err := db.GetActiveHalt(book.symbol); err == nil {
+		book.halt = halt
+	}
+	if phase, err := db.GetTradingPhase(book.symbol); err == nil {
+		book.phase = phase
+	}
+	book.refreshIndicative()
+}
+
+// startBook creates the book for a symbol from its open orders, last trade price,
+// halt state and phase and starts its sequencer. ob.mu must be held.
+func (ob *OrderBook) startBook(symbol string, orders []models.Order) *symbolBook {
+	book := newSymbolBook(symbol)
+	book.reset(orders)
+	restoreState(book)
+	ob.books[symbol] = book
+	go ob.run(book)
+	return book
+}
+
+// bookFor returns the book for a symbol, creating it and starting its sequencer if needed
+func (ob *OrderBook) bookFor(symbol string) *symbolBook {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	book, ok := ob.books[symbol]
+	if !ok {
+		book = ob.startBook(symbol, nil)
+	}
+	return book
+}
+
+// crosses reports whether an incoming order can trade against a resting price level.
+// Market orders cross every level up to their protection price.
+func crosses(order *models.Order, lvl *priceLevel) bool {
+	if order.Type == "market" {
+		return withinProtection(order, *lvl.price)
+	}
+	if order.Price == nil {
+		return false
+	}
+	if order.Side == "buy" {
+		return order.Price.Cmp(*lvl.price) >= 0
+	}
+	return order.Price.Cmp(*lvl.price) <= 0
+}
+
+// withinProtection reports whether a market order may trade at price: buys up to
+// and sells down to their protection price. Orders without one are unprotected.
+func withinProtection(order *models.Order, price models.Decimal) bool {
+	if order.ProtectionPrice == nil {
+		return true
+	}
+	if order.Side == "buy" {
+		return price.Cmp(*order.ProtectionPrice) <= 0
+	}
+	return price.Cmp(*order.ProtectionPrice) >= 0
+}
+
+// bestMatch returns the resting order the incoming order should trade with next, if any.
+// Resting market orders come first. Against an incoming market order they trade at
+// the last trade price, so they are skipped before the first trade or when that
+// price is beyond the incoming order's protection.
+func bestMatch(book *symbolBook, order *models.Order) *bookEntry {
+	opposite := book.oppositeOf(order.Side)
+	if e := opposite.market.orders.Front(); e != nil {
+		switch {
+		case order.Type != "market" && order.Price != nil:
+			return e.Value.(*bookEntry)
+		case order.Type == "market" && book.lastPrice != nil && withinProtection(order, *book.lastPrice):
+			return e.Value.(*bookEntry)
+		}
+	}
+	lvl := opposite.bestLevel()
+	if lvl == nil || !crosses(order, lvl) {
+		return nil
+	}
+	return lvl.orders.Front().Value.(*bookEntry)
+}
+
+// tradePrice returns the execution price for a pair, preferring the ask price.
+// Two market orders trade at the last trade price; without one they cannot be
+// priced and ok is false.
+func tradePrice(book *symbolBook, bid, ask *models.Order) (price models.Decimal, ok bool) {
+	switch {
+	case ask.Price != nil:
+		return *ask.Price, true
+	case bid.Price != nil:
+		return *bid.Price, true
+	case book.lastPrice != nil:
+		return *book.lastPrice, true
+	}
+	return models.Decimal{}, false
+}
+
+// allocation is the quantity a resting order trades with an incoming order
+type allocation struct {
+	order    *models.Order
+	quantity models.Decimal
+}
+
+// allocate divides what an incoming order takes from the level of entry among the
+// orders resting there, using the matching algorithm of the instrument. Resting
+// market orders trade one at a time in time priority.
+func (ob *OrderBook) allocate(book *symbolBook, order *models.Order, entry *bookEntry) []allocation {
+	if entry.level.price == nil {
+		return []allocation{{entry.order, models.MinDecimal(order.RemainingQuantity, visibleQuantity(entry.order))}}
+	}
+	level := LevelAllocation{Lot: allocationLot}
+	var algorithm MatchingAlgorithm = FIFO{}
+	if inst, ok := ob.Instruments.Get(book.symbol); ok {
+		level.Lot = inst.LotSize
+		algorithm = algorithmFor(inst)
+	}
+	var total models.Decimal
+	for e := entry.level.orders.Front(); e != nil; e = e.Next() {
+		resting := e.Value.(*bookEntry).order
+		level.Orders = append(level.Orders, resting)
+		total = total.Add(visibleQuantity(resting))
+	}
+	level.Quantity = models.MinDecimal(order.RemainingQuantity, total)
+	if top := entry.side.top; top != nil && book.index[top.ID].level == entry.level {
+		level.Top = top
+	}
 
-				matched = true
+	var allocations []allocation
+	for i, quantity := range algorithm.Allocate(level) {
+		if quantity.Sign() > 0 {
+			allocations = append(allocations, allocation{level.Orders[i], quantity})
+		}
+	}
+	return allocations
+}
+
+// matchOrders performs the core matching logic for an incoming order against the book within a transaction.
+// The best level is allocated among its orders by the instrument's matching algorithm, then each allocation
+// trades in turn; the next level is taken once the allocations are done.
+func (ob *OrderBook) matchOrders(book *symbolBook, order *models.Order, tx *sql.Tx) (bool, error) {
+	matched := false
+
+	for order.RemainingQuantity.Sign() > 0 && book.matching() {
+		entry := bestMatch(book, order)
+		if entry == nil {
+			break
+		}
+		for _, a := range ob.allocate(book, order, entry) {
+			if order.RemainingQuantity.Sign() == 0 || !book.matching() {
+				break
 			}
-			if bid.RemainingQuantity == 0 {
-				bids = bids[1:]
+			if book.get(a.order.ID) == nil {
+				continue // left the book earlier in this allocation
 			}
-			if ask.RemainingQuantity == 0 {
-				asks = asks[1:]
-			} else {
-				asks[0] = ask
+			stop, traded, err := ob.matchResting(book, order, a.order, a.quantity, tx)
+			matched = matched || traded
+			if err != nil || stop {
+				return matched, err
 			}
+		}
+	}
+	return matched, nil
+}
+
+// matchResting trades an incoming order against one resting order for up to
+// quantity, after self-trade prevention, market protection, the circuit breaker
+// and the funds check. It reports whether the incoming order is done matching
+// and whether a trade happened.
+func (ob *OrderBook) matchResting(book *symbolBook, order, resting *models.Order, quantity models.Decimal, tx *sql.Tx) (stop, traded bool, err error) {
+	if isSelfTrade(order, resting) {
+		stop, err := ob.preventSelfTrade(book, order, resting, tx)
+		return stop, false, err
+	}
+
+	bid, ask := order, resting
+	if order.Side != "buy" {
+		bid, ask = resting, order
+	}
+	price, ok := tradePrice(book, bid, ask)
+	if !ok {
+		log.Printf("Market orders %d and %d cannot be priced before the first trade", bid.ID, ask.ID)
+		return true, false, nil
+	}
+
+	// A resting market order never trades beyond its own protection price
+	if !withinProtection(resting, price) {
+		if err := ob.cancelProtected(book, resting, price, tx); err != nil {
+			return true, false, err
+		}
+		book.remove(resting.ID)
+		return false, false, nil
+	}
+
+	// A trade that would move the price beyond the circuit breaker halts the symbol instead
+	if halted, err := ob.checkCircuitBreaker(book, price, tx); err != nil || halted {
+		return true, false, err
+	}
+
+	// A market buy only trades while its hold covers the price
+	if !canAfford(bid, price) {
+		if bid == order {
+			log.Printf("Buy order %d stopped matching at %s, beyond the funds held", order.ID, price)
+			return true, false, nil
+		}
+		if err := ob.cancelRemainder(book, resting, tx); err != nil {
+			return true, false, err
+		}
+		book.remove(resting.ID)
+		log.Printf("Resting buy order %d canceled, its hold does not cover %s", resting.ID, price)
+		return false, false, nil
+	}
+
+	// Only the visible slice of a resting iceberg trades at its queue position
+	quantity = models.MinDecimal(quantity, models.MinDecimal(order.RemainingQuantity, visibleQuantity(resting)))
+	if err := ob.fill(book, order, resting, price, quantity, tx); err != nil {
+		return true, false, err
+	}
+	return false, true, nil
+}
+
+// fill executes quantity between a taker and a maker order at price: it logs and
+// settles the trade, updates both orders and the last trade price, and takes filled
+// orders out of the book. An iceberg in the book whose slice is used up refreshes
+// it at the back of its price level.
+func (ob *OrderBook) fill(book *symbolBook, taker, maker *models.Order, price, quantity models.Decimal, tx *sql.Tx) error {
+	bid, ask := taker, maker
+	if taker.Side != "buy" {
+		bid, ask = maker, taker
+	}
+	trade, err := ob.logTrade(bid, ask, taker.Side, price, quantity, tx)
+	if err != nil {
+		log.Printf("Failed to log trade for orders %d and %d: %v", bid.ID, ask.ID, err)
+		return err
+	}
+	if err := ob.settleTrade(trade, bid, ask, tx); err != nil {
+		return err
+	}
+	book.trades = append(book.trades, trade)
+	bid.RemainingQuantity = bid.RemainingQuantity.Sub(quantity)
+	ask.RemainingQuantity = ask.RemainingQuantity.Sub(quantity)
+	updateOrderStatus(bid)
+	updateOrderStatus(ask)
+	book.reportFill(bid, trade)
+	book.reportFill(ask, trade)
+
+	var refreshed []*models.Order
+	for _, order := range []*models.Order{bid, ask} {
+		if book.get(order.ID) == nil || !isIceberg(order) {
 			continue
 		}
+		order.VisibleQuantity = order.VisibleQuantity.Sub(quantity)
+		if order.VisibleQuantity.IsZero() && order.RemainingQuantity.Sign() > 0 {
+			refreshSlice(order)
+			refreshed = append(refreshed, order)
+		}
+	}
 
-		if bid.Price != nil && ask.Price != nil && *bid.Price >= *ask.Price {
-			pairKey := fmt.Sprintf("%d-%d", bid.ID, ask.ID)
-			if !processedPairs[pairKey] {
-				quantity := min(bid.RemainingQuantity, ask.RemainingQuantity)
-				if quantity > 0 {
-					bid.RemainingQuantity -= quantity
-					ask.RemainingQuantity -= quantity
-					updateOrderStatus(bid)
-					updateOrderStatus(ask)
-
-					if err := db.UpdateOrderTx(bid, tx); err != nil {
-						log.Printf("Failed to update bid order %d: %v", bid.ID, err)
-						return false
-					}
-					if err := db.UpdateOrderTx(ask, tx); err != nil {
-						log.Printf("Failed to update ask order %d: %v", ask.ID, err)
-						return false
-					}
-
-					if err := logTrade(bid, ask, *ask.Price, quantity, tx); err != nil {
-						log.Printf("Failed to log trade for orders %d and %d: %v", bid.ID, ask.ID, err)
-						return false
-					}
-
-					processedPairs[pairKey] = true
-					matched = true
-				}
-				if ask.RemainingQuantity == 0 {
-					asks = asks[1:]
-				} else {
-					asks[0] = ask
+	if err := db.UpdateOrderTx(bid, tx); err != nil {
+		log.Printf("Failed to update bid order %d: %v", bid.ID, err)
+		return err
+	}
+	if err := db.UpdateOrderTx(ask, tx); err != nil {
+		log.Printf("Failed to update ask order %d: %v", ask.ID, err)
+		return err
+	}
+	book.lastPrice = &price
+	ob.recordPrice(book, price)
+
+	for _, order := range []*models.Order{bid, ask} {
+		if order.RemainingQuantity.IsZero() {
+			book.remove(order.ID)
+		}
+	}
+	for _, order := range refreshed {
+		// A refreshed slice loses time priority within its level
+		book.remove(order.ID)
+		book.add(order)
+		log.Printf("Iceberg order %d refreshed with visible quantity %s, hidden %s", order.ID, order.VisibleQuantity, order.RemainingQuantity.Sub(order.VisibleQuantity))
+	}
+	return nil
+}
+
+// isSelfTrade reports whether an incoming order with self-trade prevention would
+// trade against a resting order of its own account
+func isSelfTrade(order, resting *models.Order) bool {
+	return order.STPMode != "" && order.AccountID != 0 && order.AccountID == resting.AccountID
+}
+
+// preventSelfTrade applies the self-trade prevention mode of the incoming order
+// instead of trading, records an STP event for both orders and reports whether
+// the incoming order is done matching
+func (ob *OrderBook) preventSelfTrade(book *symbolBook, order, resting *models.Order, tx *sql.Tx) (bool, error) {
+	details := fmt.Sprintf("%s: order %d and resting order %d belong to account %d", order.STPMode, order.ID, resting.ID, order.AccountID)
+	cancelIncoming, cancelResting := false, false
+
+	switch order.STPMode {
+	case STPCancelNewest:
+		cancelIncoming = true
+	case STPCancelOldest:
+		cancelResting = true
+	case STPCancelBoth:
+		cancelIncoming, cancelResting = true, true
+	case STPDecrementAndCancel:
+		quantity := models.MinDecimal(order.RemainingQuantity, visibleQuantity(resting))
+		if err := ob.releaseHold(order, holdPortion(order, quantity), tx); err != nil {
+			return false, err
+		}
+		if err := ob.releaseHold(resting, holdPortion(resting, quantity), tx); err != nil {
+			return false, err
+		}
+		order.RemainingQuantity = order.RemainingQuantity.Sub(quantity)
+		resting.RemainingQuantity = resting.RemainingQuantity.Sub(quantity)
+		details += fmt.Sprintf(", both decremented by %s", quantity)
+		cancelIncoming = order.RemainingQuantity.IsZero()
+		cancelResting = resting.RemainingQuantity.IsZero()
+
+		if !cancelIncoming {
+			order.UpdatedAt = time.Now()
+			if err := db.UpdateOrderTx(order, tx); err != nil {
+				log.Printf("Failed to decrement order %d: %v", order.ID, err)
+				return false, err
+			}
+			book.report(order, models.ExecRestated)
+		}
+		if !cancelResting {
+			requeue := false
+			if isIceberg(resting) {
+				resting.VisibleQuantity = resting.VisibleQuantity.Sub(quantity)
+				if resting.VisibleQuantity.IsZero() {
+					refreshSlice(resting)
+					requeue = true
 				}
-			} else {
-				asks = asks[1:]
 			}
-		} else {
-			asks = asks[1:] // Move to next ask if price condition fails
+			resting.UpdatedAt = time.Now()
+			if err := db.UpdateOrderTx(resting, tx); err != nil {
+				log.Printf("Failed to decrement order %d: %v", resting.ID, err)
+				return false, err
+			}
+			book.report(resting, models.ExecRestated)
+			if requeue {
+				book.remove(resting.ID)
+				book.add(resting)
+			}
 		}
-		if bid.RemainingQuantity == 0 {
-			bids = bids[1:]
+	}
+
+	if cancelResting {
+		if err := ob.cancelRemainder(book, resting, tx); err != nil {
+			return false, err
+		}
+		book.remove(resting.ID)
+	}
+	if cancelIncoming {
+		if err := ob.cancelRemainder(book, order, tx); err != nil {
+			return false, err
 		}
 	}
-	return matched
+
+	if err := recordEvent(order, OrderEventSelfTradePrevented, details, tx); err != nil {
+		return false, err
+	}
+	if err := recordEvent(resting, OrderEventSelfTradePrevented, details, tx); err != nil {
+		return false, err
+	}
+	log.Printf("Self-trade prevented for %s: %s", order.Symbol, details)
+	return cancelIncoming, nil
+}
+
+// fillableQuantity returns how much of an order could trade against the opposite side
+// right now, stopping once the full quantity is covered
+func fillableQuantity(book *symbolBook, order *models.Order) models.Decimal {
+	var available models.Decimal
+	opposite := book.oppositeOf(order.Side)
+	levels := opposite.levels
+	if order.Type != "market" && order.Price != nil ||
+		order.Type == "market" && book.lastPrice != nil && withinProtection(order, *book.lastPrice) {
+		levels = append([]*priceLevel{opposite.market}, levels...)
+	}
+	for _, lvl := range levels {
+		if lvl.price != nil && !crosses(order, lvl) {
+			break
+		}
+		for e := lvl.orders.Front(); e != nil; e = e.Next() {
+			resting := e.Value.(*bookEntry).order
+			if isSelfTrade(order, resting) {
+				if order.STPMode == STPCancelOldest {
+					continue
+				}
+				return available
+			}
+			available = available.Add(resting.RemainingQuantity)
+			if available.Cmp(order.Quantity) >= 0 {
+				return available
+			}
+		}
+	}
+	return available
 }
 
 // updateOrderStatus sets the status based on remaining quantity
 func updateOrderStatus(order *models.Order) {
 	order.UpdatedAt = time.Now()
-	if order.RemainingQuantity == 0 {
+	if order.RemainingQuantity.IsZero() {
 		order.Status = OrderStatusFilled
 	} else {
 		order.Status = OrderStatusPartiallyFilled
 	}
 }
 
-// logTrade records a trade in the database with duplicate handling
-func logTrade(bid, ask *models.Order, price float64, quantity int, tx *sql.Tx) error {
+// logTrade records a trade in the database with duplicate handling. The side of
+// the incoming order pays the taker fee, the resting side the maker fee.
+func (ob *OrderBook) logTrade(bid, ask *models.Order, takerSide string, price, quantity models.Decimal, tx *sql.Tx) (*models.Trade, error) {
 	trade := &models.Trade{
-		Symbol:      bid.Symbol,
-		BuyOrderID:  bid.ID,
-		SellOrderID: ask.ID,
-		Price:       price,
-		Quantity:    quantity,
-		CreatedAt:   time.Now(),
-	}
-	if err := db.CreateTrade(trade); err != nil {
+		Symbol:        bid.Symbol,
+		BuyOrderID:    bid.ID,
+		SellOrderID:   ask.ID,
+		BuyAccountID:  bid.AccountID,
+		SellAccountID: ask.AccountID,
+		Price:         price,
+		Quantity:      quantity,
+		TakerSide:     takerSide,
+		CreatedAt:     time.Now(),
+	}
+	if err := ob.applyFees(trade); err != nil {
+		return nil, err
+	}
+	if err := db.CreateTradeTx(trade, tx); err != nil {
 		if err, ok := err.(*mysql.MySQLError); ok && err.Number == 1062 { // Duplicate entry
 			log.Printf("Duplicate trade ignored: BuyOrderID=%d, SellOrderID=%d, Error: %v", bid.ID, ask.ID, err)
-			return nil
+			return trade, nil
 		}
-		return err
+		return nil, err
 	}
-	log.Printf("Trade logged: %s, Price: %.2f, Quantity: %d", trade.Symbol, trade.Price, trade.Quantity)
-	return nil
+	ob.Fees.addVolume(trade)
+	log.Printf("Trade logged: %s, Price: %s, Quantity: %s", trade.Symbol, trade.Price, trade.Quantity)
+	return trade, nil
 }
 
-// MatchOrders processes a new order and attempts to match it with existing orders
-func (ob *OrderBook) MatchOrders(newOrder *models.Order) error {
+// processNewOrder runs the pre-trade risk checks, then inserts a new order and
+// matches it against the book in one transaction. Stop orders are parked in the
+// trigger book instead; any stops triggered by the resulting trades are executed
+// in the same transaction.
+func (ob *OrderBook) processNewOrder(book *symbolBook, newOrder *models.Order) (err error) {
+	if err := ob.checkRisk(book, newOrder); err != nil {
+		return err
+	}
+	if err := checkPhase(book, newOrder); err != nil {
+		return err
+	}
+
+	var repricedFrom *models.Decimal
+	if newOrder.PostOnly {
+		original := *newOrder.Price
+		if err := ob.applyPostOnly(book, newOrder); err != nil {
+			log.Printf("Rejected post-only order for %s at %s: %v", newOrder.Symbol, original, err)
+			return err
+		}
+		if !newOrder.Price.Equal(original) {
+			repricedFrom = &original
+		}
+	}
+
+	// Market orders never trade beyond the collar around the current price
+	if newOrder.Type == "market" {
+		newOrder.ProtectionPrice = ob.protectionPrice(book, newOrder)
+	}
+
+	if newOrder.TimeInForce == TimeInForceFOK && !isStop(newOrder) {
+		available := fillableQuantity(book, newOrder)
+		if available.Cmp(newOrder.Quantity) < 0 {
+			log.Printf("Rejected fill-or-kill order for %s: quantity %s, available %s", newOrder.Symbol, newOrder.Quantity, available)
+			return reject(RejectFOKNotFillable, "Fill-or-kill order cannot be filled completely: quantity %s, available %s", newOrder.Quantity, available)
+		}
+	}
+
+	if newOrder.Hold, err = ob.requiredHold(newOrder); err != nil {
+		return err
+	}
+
 	tx, err := db.DB.Begin()
 	if err != nil {
 		log.Printf("Failed to begin transaction: %v", err)
 		return err
 	}
+	// The book is untouched until the funds are held, so only later failures
+	// need it reloaded
+	touched := false
+	var tripped *models.Halt // a circuit breaker halt that outlives the rollback
 	defer func() {
 		if err != nil {
 			tx.Rollback()
+			if touched {
+				ob.reload(book)
+			}
 			log.Printf("Transaction rolled back for order %d due to error: %v", newOrder.ID, err)
+			if tripped != nil {
+				if err := ob.saveHalt(book, tripped); err != nil {
+					log.Printf("Failed to keep circuit breaker halt of %s: %v", book.symbol, err)
+				}
+			}
 		}
 	}()
 
 	newOrder.CreatedAt = time.Now()
 	newOrder.UpdatedAt = newOrder.CreatedAt
-	if err := db.CreateOrderTx(newOrder, tx); err != nil {
+	newOrder.QueuedAt = newOrder.CreatedAt
+	if err = db.CreateOrderTx(newOrder, tx); err != nil {
 		log.Printf("Failed to create order %d: %v", newOrder.ID, err)
 		return err
 	}
 
-	existingOrders, err := db.GetOrderBook(newOrder.Symbol, true)
-	if err != nil {
-		log.Printf("Failed to get order book for %s: %v", newOrder.Symbol, err)
+	// Reserve the funds the order needs before it can trade
+	if err = ob.postHold(newOrder, newOrder.Hold, tx); err != nil {
+		log.Printf("Rejected order for %s from account %d: %v", newOrder.Symbol, newOrder.AccountID, err)
 		return err
 	}
+	touched = true
+	book.report(newOrder, models.ExecAccepted)
 
-	if ob.Orders[newOrder.Symbol] == nil {
-		ob.Orders[newOrder.Symbol] = make([]*models.Order, 0)
+	if repricedFrom != nil {
+		details := fmt.Sprintf("post-only order repriced from %s to %s to avoid crossing the book", repricedFrom, newOrder.Price)
+		if err = recordEvent(newOrder, OrderEventPostOnlyRepriced, details, tx); err != nil {
+			return err
+		}
 	}
-	ob.Orders[newOrder.Symbol] = append(ob.Orders[newOrder.Symbol], newOrder)
 
-	var bids, asks []*models.Order
-	for _, order := range existingOrders {
-		ord := &models.Order{
-			ID:               order.ID,
-			Symbol:           order.Symbol,
-			Side:             order.Side,
-			Type:             order.Type,
-			Price:            order.Price,
-			Quantity:         order.Quantity,
-			RemainingQuantity: order.RemainingQuantity,
-			Status:           order.Status,
-			CreatedAt:        order.CreatedAt,
-			UpdatedAt:        order.UpdatedAt,
-		}
-		if ord.Side == "buy" {
-			bids = append(bids, ord)
-		} else {
-			asks = append(asks, ord)
-		}
+	if isStop(newOrder) {
+		book.add(newOrder)
+		log.Printf("Stop order %d parked in trigger book at stop price %s", newOrder.ID, newOrder.StopPrice)
+	} else if err = ob.execute(book, newOrder, tx); err != nil {
+		return err
+	} else if newOrder.TimeInForce == TimeInForceFOK && newOrder.RemainingQuantity.Sign() > 0 {
+		// Matching can stop short of the quantity found fillable, for instance when
+		// the circuit breaker halts the symbol; the rollback undoes the partial fills,
+		// and a halt is stored again on its own so the breaker trip is not lost
+		tripped = book.halt
+		log.Printf("Rejected fill-or-kill order for %s: matching stopped with %s remaining", newOrder.Symbol, newOrder.RemainingQuantity)
+		err = reject(RejectFOKNotFillable, "Fill-or-kill order cannot be filled completely: matching stopped with %s of %s remaining", newOrder.RemainingQuantity, newOrder.Quantity)
+		return err
 	}
-	if newOrder.Side == "buy" {
-		bids = append(bids, newOrder)
-	} else {
-		asks = append(asks, newOrder)
+
+	if err = ob.processTriggers(book, tx); err != nil {
+		return err
+	}
+
+	if err = ob.commit(book, tx); err != nil {
+		log.Printf("Failed to commit transaction for order %d: %v", newOrder.ID, err)
+		return err
+	}
+	log.Printf("Transaction committed successfully for order %d", newOrder.ID)
+	return nil
+}
+
+// execute matches an order against the book and then rests or cancels its
+// remainder according to its type and time in force
+func (ob *OrderBook) execute(book *symbolBook, order *models.Order, tx *sql.Tx) error {
+	matched, err := ob.matchOrders(book, order, tx)
+	if err != nil {
+		return err
+	}
+	log.Printf("Processed order %d (type: %s), matched: %v, resting orders: %d", order.ID, order.Type, matched, len(book.index))
+	if order.Status == OrderStatusCanceled {
+		return nil // canceled by self-trade prevention
 	}
 
-	sort.Slice(bids, func(i, j int) bool {
-		if bids[i].Price != nil && bids[j].Price != nil {
-			if *bids[i].Price != *bids[j].Price {
-				return *bids[i].Price > *bids[j].Price
+	if order.RemainingQuantity.Sign() > 0 {
+		lvl := book.oppositeOf(order.Side).bestLevel()
+		switch {
+		case order.Type == "market" && lvl != nil && !crosses(order, lvl):
+			if err := ob.cancelProtected(book, order, *lvl.price, tx); err != nil {
+				return err
+			}
+		case order.Type != "market" && order.Type != "limit":
+			if err := ob.cancelRemainder(book, order, tx); err != nil {
+				return err
+			}
+			log.Printf("No match for order %d, canceled with remaining quantity %s due to invalid type", order.ID, order.RemainingQuantity)
+		case order.TimeInForce != TimeInForceGTC:
+			if err := ob.cancelRemainder(book, order, tx); err != nil {
+				return err
+			}
+			log.Printf("%s order %d canceled with unfilled remaining quantity %s", order.TimeInForce, order.ID, order.RemainingQuantity)
+		default:
+			if isIceberg(order) {
+				refreshSlice(order)
+				if err := db.UpdateOrderTx(order, tx); err != nil {
+					log.Printf("Failed to update iceberg order %d: %v", order.ID, err)
+					return err
+				}
+			}
+			book.add(order)
+			if !matched {
+				log.Printf("No match for %s order %d, remaining quantity %s, status remains open", order.Type, order.ID, order.RemainingQuantity)
 			}
 		}
-		return bids[i].CreatedAt.Before(bids[j].CreatedAt)
-	})
-	sort.Slice(asks, func(i, j int) bool {
-		if asks[i].Price != nil && asks[j].Price != nil {
-			if *asks[i].Price != *asks[j].Price {
-				return *asks[i].Price < *asks[j].Price
+	}
+	return nil
+}
+
+// processTriggers converts every stop reached by the last trade price into a
+// market or limit order and executes it, until no further stop is triggered
+func (ob *OrderBook) processTriggers(book *symbolBook, tx *sql.Tx) error {
+	for order := book.nextTriggered(); order != nil; order = book.nextTriggered() {
+		book.remove(order.ID)
+
+		stopType := order.Type
+		if order.Type == "stop" {
+			order.Type = "market"
+			order.ProtectionPrice = ob.protectionPrice(book, order)
+		} else {
+			order.Type = "limit"
+		}
+		order.UpdatedAt = time.Now()
+		if err := db.UpdateOrderTx(order, tx); err != nil {
+			log.Printf("Failed to convert triggered order %d: %v", order.ID, err)
+			return err
+		}
+
+		details := fmt.Sprintf("%s order triggered at last price %s (stop price %s), converted to %s", stopType, book.lastPrice, order.StopPrice, order.Type)
+		if err := recordEvent(order, OrderEventTriggered, details, tx); err != nil {
+			return err
+		}
+		book.report(order, models.ExecTriggered)
+		log.Printf("Stop order %d triggered at last price %s", order.ID, book.lastPrice)
+
+		if order.TimeInForce == TimeInForceFOK {
+			available := fillableQuantity(book, order)
+			if available.Cmp(order.RemainingQuantity) < 0 {
+				if err := ob.cancelRemainder(book, order, tx); err != nil {
+					return err
+				}
+				log.Printf("Triggered fill-or-kill order %d canceled: quantity %s, available %s", order.ID, order.RemainingQuantity, available)
+				continue
 			}
 		}
-		return asks[i].CreatedAt.Before(asks[j].CreatedAt)
-	})
-
-	matched := ob.matchOrders(bids, asks, tx)
-	log.Printf("Processed order %d (type: %s), matched: %v, bids: %d, asks: %d", newOrder.ID, newOrder.Type, matched, len(bids), len(asks))
-
-	if !matched && newOrder.Type == "market" && newOrder.RemainingQuantity > 0 {
-		log.Printf("No match for market order %d, remaining quantity %d, status remains open", newOrder.ID, newOrder.RemainingQuantity)
-	} else if !matched && newOrder.Type == "limit" && newOrder.RemainingQuantity > 0 {
-		log.Printf("No match for limit order %d, remaining quantity %d, status remains open", newOrder.ID, newOrder.RemainingQuantity)
-	} else if !matched && newOrder.RemainingQuantity > 0 && newOrder.Type != "market" && newOrder.Type != "limit" {
-		newOrder.Status = OrderStatusCanceled
-		newOrder.UpdatedAt = time.Now()
-		if err := db.UpdateOrderTx(newOrder, tx); err != nil {
-			log.Printf("Failed to cancel order %d: %v", newOrder.ID, err)
+		if err := ob.execute(book, order, tx); err != nil {
 			return err
 		}
-		log.Printf("No match for order %d, canceled with remaining quantity %d due to invalid type", newOrder.ID, newOrder.RemainingQuantity)
 	}
+	return nil
+}
 
-	if err := tx.Commit(); err != nil {
-		log.Printf("Failed to commit transaction for order %d: %v", newOrder.ID, err)
+// applyPostOnly makes sure a post-only order will rest without taking liquidity,
+// either by rejecting it or by repricing it one tick behind the opposite touch
+func (ob *OrderBook) applyPostOnly(book *symbolBook, order *models.Order) error {
+	entry := bestMatch(book, order)
+	if entry == nil {
+		return nil
+	}
+	if entry.level.price == nil {
+		return reject(RejectPostOnlyWouldCross, "Post-only order would trade against a resting market order")
+	}
+	touch := *entry.level.price
+	if order.PostOnlyMode != PostOnlyReprice {
+		return reject(RejectPostOnlyWouldCross, "Post-only order at %s would cross the best opposite price %s", order.Price, touch)
+	}
+
+	inst, ok := ob.Instruments.Get(order.Symbol)
+	if !ok {
+		return reject(RejectUnknownSymbol, "Unknown symbol: %s", order.Symbol)
+	}
+	price := touch.Add(inst.TickSize)
+	if order.Side == "buy" {
+		price = touch.Sub(inst.TickSize)
+	}
+	if price.Sign() <= 0 {
+		return reject(RejectPostOnlyWouldCross, "Post-only order cannot be repriced below the best opposite price %s", touch)
+	}
+	price = price.Rescale(inst.PricePrecision)
+	order.Price = &price
+	log.Printf("Post-only order for %s repriced to %s, one tick from the touch %s", order.Symbol, price, touch)
+	return nil
+}
+
+// recordEvent stores an order event within the matching transaction
+func recordEvent(order *models.Order, eventType, details string, tx *sql.Tx) error {
+	event := &models.OrderEvent{
+		OrderID:   order.ID,
+		Symbol:    order.Symbol,
+		Type:      eventType,
+		Details:   details,
+		CreatedAt: time.Now(),
+	}
+	if err := db.CreateOrderEventTx(event, tx); err != nil {
+		log.Printf("Failed to record %s event for order %d: %v", eventType, order.ID, err)
 		return err
 	}
-	log.Printf("Transaction committed successfully for order %d", newOrder.ID)
 	return nil
-}
\ No newline at end of file
+}
+
+// cancelRemainder cancels the unfilled remainder of an order that will not rest
+// in the book and releases the funds still held for it
+func (ob *OrderBook) cancelRemainder(book *symbolBook, order *models.Order, tx *sql.Tx) error {
+	if err := ob.setHold(order, models.Decimal{}, tx); err != nil {
+		return err
+	}
+	order.Status = OrderStatusCanceled
+	order.UpdatedAt = time.Now()
+	if err := db.UpdateOrderTx(order, tx); err != nil {
+		log.Printf("Failed to cancel order %d: %v", order.ID, err)
+		return err
+	}
+	book.report(order, models.ExecCanceled)
+	return nil
+}
+
+// cancelProtected cancels the remainder of a market order that would next have
+// to trade at price, beyond its protection price
+func (ob *OrderBook) cancelProtected(book *symbolBook, order *models.Order, price models.Decimal, tx *sql.Tx) error {
+	details := fmt.Sprintf("remaining quantity %s canceled, price %s is beyond the protection price %s", order.RemainingQuantity, price, order.ProtectionPrice)
+	if err := ob.cancelRemainder(book, order, tx); err != nil {
+		return err
+	}
+	log.Printf("Market order %d: %s", order.ID, details)
+	return recordEvent(order, OrderEventMarketProtection, details, tx)
+}
+
+// processStatusChange changes the status and remaining quantity of a resting order.
+// A nil remaining quantity leaves it unchanged. Filled and canceled orders leave
+// the book and release their hold; otherwise the hold follows the remaining quantity.
+func (ob *OrderBook) processStatusChange(book *symbolBook, orderID int64, status string, remainingQuantity *models.Decimal) (*models.Order, error) {
+	order := book.get(orderID)
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+
+	updated := *order
+	updated.Status = status
+	if remainingQuantity != nil {
+		updated.RemainingQuantity = *remainingQuantity
+		if isIceberg(&updated) {
+			updated.VisibleQuantity = models.MinDecimal(updated.VisibleQuantity, updated.RemainingQuantity)
+			if updated.VisibleQuantity.IsZero() {
+				refreshSlice(&updated)
+			}
+		}
+	}
+	updated.UpdatedAt = time.Now()
+
+	hold := models.Decimal{}
+	if status != OrderStatusFilled && status != OrderStatusCanceled {
+		if updated.Side == "buy" && updated.Price == nil {
+			hold = holdPortion(order, updated.RemainingQuantity)
+		} else {
+			var err error
+			if hold, err = ob.requiredHold(&updated); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Printf("Failed to begin transaction: %v", err)
+		return nil, err
+	}
+	if err := ob.setHold(&updated, hold, tx); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := db.UpdateOrderTx(&updated, tx); err != nil {
+		tx.Rollback()
+		log.Printf("Failed to update order %d: %v", orderID, err)
+		return nil, err
+	}
+	execType := models.ExecRestated
+	if status == OrderStatusCanceled {
+		execType = models.ExecCanceled
+	}
+	book.report(&updated, execType)
+	if err := ob.commit(book, tx); err != nil {
+		tx.Rollback()
+		log.Printf("Failed to commit status change of order %d: %v", orderID, err)
+		return nil, err
+	}
+
+	*order = updated
+	if status == OrderStatusFilled || status == OrderStatusCanceled {
+		book.remove(orderID)
+	}
+	return order, nil
+}
+
+// processAmend changes the price and/or total quantity of a resting limit order.
+// A quantity reduction keeps queue priority; a price change or quantity increase
+// sends the order to the back of its new price level. An order amended to a
+// crossing price matches immediately, in the same transaction as the amendment.
+func (ob *OrderBook) processAmend(book *symbolBook, orderID int64, price *models.Decimal, quantity models.Decimal) (order *models.Order, err error) {
+	order = book.get(orderID)
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+	if order.Type != "limit" {
+		return nil, ErrAmendNotAllowed
+	}
+
+	updated := *order
+	if price != nil {
+		p := *price
+		updated.Price = &p
+	}
+	if quantity.Sign() > 0 {
+		updated.Quantity = quantity
+	}
+	filled := order.Quantity.Sub(order.RemainingQuantity)
+	if updated.Quantity.Cmp(filled) <= 0 {
+		return nil, ErrAmendQuantity
+	}
+	updated.RemainingQuantity = updated.Quantity.Sub(filled)
+	updated.UpdatedAt = time.Now()
+
+	priceChanged := !updated.Price.Equal(*order.Price)
+	losesPriority := priceChanged || updated.Quantity.Cmp(order.Quantity) > 0
+	if losesPriority {
+		updated.QueuedAt = updated.UpdatedAt
+	}
+	if isIceberg(&updated) {
+		if losesPriority {
+			refreshSlice(&updated)
+		} else {
+			updated.VisibleQuantity = models.MinDecimal(updated.VisibleQuantity, updated.RemainingQuantity)
+		}
+	}
+	if err := ob.checkRisk(book, &updated); err != nil {
+		return nil, err
+	}
+	crossing := priceChanged && bestMatch(book, &updated) != nil
+	if crossing && updated.PostOnly {
+		return nil, reject(RejectPostOnlyWouldCross, "Post-only order amended to %s would cross the book", updated.Price)
+	}
+	hold, err := ob.requiredHold(&updated)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Printf("Failed to begin transaction: %v", err)
+		return nil, err
+	}
+	// The book is untouched until the amendment is stored, so only later failures
+	// need it reloaded
+	touched := false
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			if touched {
+				ob.reload(book)
+			}
+			log.Printf("Transaction rolled back for amendment of order %d due to error: %v", orderID, err)
+		}
+	}()
+
+	if err = ob.setHold(&updated, hold, tx); err != nil {
+		return nil, err
+	}
+	if err = db.UpdateOrderTx(&updated, tx); err != nil {
+		log.Printf("Failed to amend order %d: %v", orderID, err)
+		return nil, err
+	}
+	touched = true
+	book.report(&updated, models.ExecAmended)
+
+	if losesPriority {
+		book.remove(orderID)
+		*order = updated
+		if crossing {
+			if err = ob.execute(book, order, tx); err != nil {
+				return nil, err
+			}
+			if err = ob.processTriggers(book, tx); err != nil {
+				return nil, err
+			}
+		} else {
+			book.add(order)
+		}
+	} else {
+		*order = updated
+	}
+
+	if err = ob.commit(book, tx); err != nil {
+		log.Printf("Failed to commit amendment of order %d: %v", orderID, err)
+		return nil, err
+	}
+	log.Printf("Amended order %d: price %s, quantity %s, priority kept: %v, crossed: %v", orderID, *order.Price, order.Quantity, !losesPriority, crossing)
+	return order, nil
+}
@@ -0,0 +1,312 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"golang-order-matching-system/db"
+	"golang-order-matching-system/models"
+)
+
+// Reject codes returned by the pre-trade risk checks
+const (
+	RejectRiskOrderQuantity = "RISK_MAX_ORDER_QUANTITY"
+	RejectRiskNotional      = "RISK_MAX_NOTIONAL"
+	RejectRiskOpenOrders    = "RISK_MAX_OPEN_ORDERS"
+	RejectRiskPriceBand     = "RISK_PRICE_BAND"
+)
+
+// Errors returned by the risk manager
+var (
+	ErrRiskLimitsNotFound = errors.New("risk limits not found")
+	ErrInvalidRiskLimits  = errors.New("invalid risk limits")
+)
+
+// RiskContext is what a risk check sees of the account and book an order enters
+type RiskContext struct {
+	Limits     models.RiskLimits // tightest limits of the order's account on its symbol
+	OpenOrders int               // other open orders of the account on the symbol
+	LastPrice  *models.Decimal   // nil before the first trade
+	BestBid    *models.Decimal   // nil when no bid rests at a price
+	BestAsk    *models.Decimal   // nil when no ask rests at a price
+}
+
+// Mid returns the midpoint of the best bid and ask, or nil if either side is empty
+func (c *RiskContext) Mid() *models.Decimal {
+	if c.BestBid == nil || c.BestAsk == nil {
+		return nil
+	}
+	mid := c.BestBid.Add(*c.BestAsk).Quo(models.DecimalFromInt(2), holdScale)
+	return &mid
+}
+
+// ReferencePrice returns the last trade price, or the mid before the first trade
+func (c *RiskContext) ReferencePrice() *models.Decimal {
+	if c.LastPrice != nil {
+		return c.LastPrice
+	}
+	return c.Mid()
+}
+
+// RiskCheck is a pre-trade check applied by the sequencer of a symbol before an
+// order is stored or amended. It refuses an order by returning a *RejectError.
+type RiskCheck interface {
+	Check(order *models.Order, ctx *RiskContext) error
+}
+
+// RiskCheckFunc adapts a function to the RiskCheck interface
+type RiskCheckFunc func(order *models.Order, ctx *RiskContext) error
+
+// Check calls f(order, ctx)
+func (f RiskCheckFunc) Check(order *models.Order, ctx *RiskContext) error {
+	return f(order, ctx)
+}
+
+// DefaultRiskChecks are the checks every risk manager starts with
+var DefaultRiskChecks = []RiskCheck{
+	RiskCheckFunc(checkOrderQuantity),
+	RiskCheckFunc(checkNotional),
+	RiskCheckFunc(checkOpenOrders),
+	RiskCheckFunc(checkPriceBand),
+}
+
+// riskKey identifies the scope of a set of risk limits
+type riskKey struct {
+	accountID int64
+	symbol    string
+}
+
+// RiskManager caches the risk_limits table and runs the pre-trade risk checks
+type RiskManager struct {
+	mu     sync.RWMutex
+	limits map[riskKey]models.RiskLimits
+	checks []RiskCheck
+}
+
+// NewRiskManager creates a risk manager without limits running the default checks
+func NewRiskManager() *RiskManager {
+	return &RiskManager{
+		limits: make(map[riskKey]models.RiskLimits),
+		checks: append([]RiskCheck(nil), DefaultRiskChecks...),
+	}
+}
+
+// Use adds a check that runs after the existing ones
+func (m *RiskManager) Use(check RiskCheck) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checks = append(m.checks, check)
+}
+
+// Load reads all risk limits from the database
+func (m *RiskManager) Load() error {
+	limits, err := db.GetRiskLimits()
+	if err != nil {
+		log.Printf("Failed to load risk limits: %v", err)
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limits = make(map[riskKey]models.RiskLimits)
+	for _, l := range limits {
+		m.limits[riskKey{l.AccountID, l.Symbol}] = l
+	}
+	log.Printf("Risk limits loaded for %d scopes", len(limits))
+	return nil
+}
+
+// List returns all configured risk limits ordered by account and symbol
+func (m *RiskManager) List() []models.RiskLimits {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	limits := make([]models.RiskLimits, 0, len(m.limits))
+	for _, l := range m.limits {
+		limits = append(limits, l)
+	}
+	sort.Slice(limits, func(i, j int) bool {
+		if limits[i].AccountID != limits[j].AccountID {
+			return limits[i].AccountID < limits[j].AccountID
+		}
+		return limits[i].Symbol < limits[j].Symbol
+	})
+	return limits
+}
+
+// Set validates and stores the risk limits of a scope, replacing previous ones
+func (m *RiskManager) Set(l *models.RiskLimits) error {
+	switch {
+	case l.AccountID < 0:
+		return fmt.Errorf("%w: account_id must not be negative", ErrInvalidRiskLimits)
+	case l.MaxOrderQuantity.Sign() < 0 || l.MaxNotional.Sign() < 0 || l.MaxOpenOrders < 0 || l.PriceBandPct.Sign() < 0:
+		return fmt.Errorf("%w: limits must not be negative", ErrInvalidRiskLimits)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l.UpdatedAt = time.Now()
+	if err := db.SaveRiskLimits(l); err != nil {
+		return err
+	}
+	m.limits[riskKey{l.AccountID, l.Symbol}] = *l
+	return nil
+}
+
+// Delete removes the risk limits of a scope
+func (m *RiskManager) Delete(accountID int64, symbol string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := riskKey{accountID, symbol}
+	if _, ok := m.limits[key]; !ok {
+		return ErrRiskLimitsNotFound
+	}
+	if err := db.DeleteRiskLimits(accountID, symbol); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	delete(m.limits, key)
+	return nil
+}
+
+// Limits returns the tightest limits that apply to an account on a symbol, from
+// the global, instrument, account and account-on-instrument scopes
+func (m *RiskManager) Limits(accountID int64, symbol string) models.RiskLimits {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	effective := models.RiskLimits{AccountID: accountID, Symbol: symbol}
+	for _, key := range []riskKey{{0, ""}, {0, symbol}, {accountID, ""}, {accountID, symbol}} {
+		l, ok := m.limits[key]
+		if !ok {
+			continue
+		}
+		effective.MaxOrderQuantity = tighter(effective.MaxOrderQuantity, l.MaxOrderQuantity)
+		effective.MaxNotional = tighter(effective.MaxNotional, l.MaxNotional)
+		effective.PriceBandPct = tighter(effective.PriceBandPct, l.PriceBandPct)
+		if l.MaxOpenOrders > 0 && (effective.MaxOpenOrders == 0 || l.MaxOpenOrders < effective.MaxOpenOrders) {
+			effective.MaxOpenOrders = l.MaxOpenOrders
+		}
+	}
+	return effective
+}
+
+// tighter returns the smaller of two limits where zero means no limit
+func tighter(a, b models.Decimal) models.Decimal {
+	if a.IsZero() {
+		return b
+	}
+	if b.IsZero() {
+		return a
+	}
+	return models.MinDecimal(a, b)
+}
+
+// Check runs every risk check against an order and returns the first rejection
+func (m *RiskManager) Check(order *models.Order, ctx *RiskContext) error {
+	m.mu.RLock()
+	checks := m.checks
+	m.mu.RUnlock()
+	for _, check := range checks {
+		if err := check.Check(order, ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRisk builds the risk context of an order from its book and runs the risk
+// checks. The order itself is not counted among the account's open orders.
+func (ob *OrderBook) checkRisk(book *symbolBook, order *models.Order) error {
+	ctx := &RiskContext{
+		Limits:    ob.Risk.Limits(order.AccountID, order.Symbol),
+		LastPrice: book.lastPrice,
+	}
+	if lvl := book.bids.bestLevel(); lvl != nil {
+		ctx.BestBid = lvl.price
+	}
+	if lvl := book.asks.bestLevel(); lvl != nil {
+		ctx.BestAsk = lvl.price
+	}
+	ctx.OpenOrders = book.openOrders[order.AccountID]
+	if resting := book.get(order.ID); resting != nil && resting.AccountID == order.AccountID {
+		ctx.OpenOrders-- // an amended order replaces itself
+	}
+	if err := ob.Risk.Check(order, ctx); err != nil {
+		log.Printf("Risk check rejected order for %s from account %d: %v", order.Symbol, order.AccountID, err)
+		return err
+	}
+	return nil
+}
+
+// checkOrderQuantity refuses orders above the maximum order quantity
+func checkOrderQuantity(order *models.Order, ctx *RiskContext) error {
+	max := ctx.Limits.MaxOrderQuantity
+	if !max.IsZero() && order.Quantity.Cmp(max) > 0 {
+		return reject(RejectRiskOrderQuantity, "Quantity %s is above the risk limit of %s", order.Quantity, max)
+	}
+	return nil
+}
+
+// checkNotional refuses orders whose notional exceeds the maximum. Orders without
+// a limit price are valued at their stop price, the best opposite price or the
+// reference price, and pass when none is known.
+func checkNotional(order *models.Order, ctx *RiskContext) error {
+	max := ctx.Limits.MaxNotional
+	if max.IsZero() {
+		return nil
+	}
+	price := order.Price
+	if price == nil {
+		price = order.StopPrice
+	}
+	if price == nil {
+		price = ctx.BestAsk
+		if order.Side != "buy" {
+			price = ctx.BestBid
+		}
+	}
+	if price == nil {
+		price = ctx.ReferencePrice()
+	}
+	if price == nil {
+		return nil
+	}
+	notional, err := price.CheckedMulRound(order.Quantity, holdScale, models.RoundUp)
+	if err != nil {
+		return reject(RejectRiskNotional, "Notional of %s at %s is above the risk limit of %s", order.Quantity, price, max)
+	}
+	if notional.Cmp(max) > 0 {
+		return reject(RejectRiskNotional, "Notional %s is above the risk limit of %s", notional, max)
+	}
+	return nil
+}
+
+// checkOpenOrders refuses orders that would rest beyond the maximum number of open
+// orders of the account on the symbol. Orders that cannot rest are not counted.
+func checkOpenOrders(order *models.Order, ctx *RiskContext) error {
+	max := ctx.Limits.MaxOpenOrders
+	if max == 0 || (!isStop(order) && order.TimeInForce != TimeInForceGTC) {
+		return nil
+	}
+	if ctx.OpenOrders >= max {
+		return reject(RejectRiskOpenOrders, "Account already has %d open orders on %s, the risk limit is %d", ctx.OpenOrders, order.Symbol, max)
+	}
+	return nil
+}
+
+// checkPriceBand refuses limit orders priced further than the band from the last
+// trade price, or from the mid before the first trade
+func checkPriceBand(order *models.Order, ctx *RiskContext) error {
+	pct := ctx.Limits.PriceBandPct
+	ref := ctx.ReferencePrice()
+	if pct.IsZero() || order.Type != "limit" || ref == nil {
+		return nil
+	}
+	band := ref.MulQuo(pct, models.DecimalFromInt(100), holdScale)
+	if order.Price.Sub(*ref).Abs().Cmp(band) > 0 {
+		return reject(RejectRiskPriceBand, "Price %s is more than %s%% away from the reference price %s", order.Price, pct, ref)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"errors"
+
+	"golang-order-matching-system/models"
+)
+
+// commandQueueSize bounds the number of pending commands per symbol
+const commandQueueSize = 1024
+
+// commandType identifies the mutation a command applies to a book
+type commandType int
+
+const (
+	cmdNew commandType = iota
+	cmdCancel
+	cmdAmend
+	cmdStatus
+	cmdQuery
+	cmdHalt
+	cmdResume
+	cmdPhase
+)
+
+// command is a request for the sequencer of a symbol to mutate or read its book
+type command struct {
+	kind              commandType
+	order             *models.Order     // cmdNew
+	orderID           int64             // cmdCancel, cmdAmend, cmdStatus
+	price             *models.Decimal   // cmdAmend, nil keeps the current price
+	quantity          models.Decimal    // cmdAmend, zero keeps the current quantity
+	status            string            // cmdStatus
+	message           string            // cmdHalt
+	phase             string            // cmdPhase
+	remainingQuantity *models.Decimal   // cmdStatus, nil keeps the current value
+	query             func(*symbolBook) // cmdQuery, reads the book without mutating it
+	reply             chan result
+}
+
+// result is the outcome of a command, with a snapshot of the affected order or
+// halt, or the auction a phase change ran
+type result struct {
+	order   models.Order
+	halt    *models.Halt
+	auction *models.Auction
+	err     error
+}
+
+// run is the sequencer loop of a symbol: it applies commands one at a time in
+// arrival order. After each mutation it advances the engine sequence number,
+// keeps the indicative auction current and publishes market data; trades are
+// only published once their command succeeded. Rejected new orders are reported
+// to their account.
+func (ob *OrderBook) run(book *symbolBook) {
+	for cmd := range book.commands {
+		var order *models.Order
+		var halt *models.Halt
+		var auction *models.Auction
+		var err error
+		switch cmd.kind {
+		case cmdNew:
+			order = cmd.order
+			err = ob.processNewOrder(book, order)
+		case cmdCancel:
+			order, err = ob.processStatusChange(book, cmd.orderID, OrderStatusCanceled, nil)
+		case cmdAmend:
+			order, err = ob.processAmend(book, cmd.orderID, cmd.price, cmd.quantity)
+		case cmdStatus:
+			order, err = ob.processStatusChange(book, cmd.orderID, cmd.status, cmd.remainingQuantity)
+		case cmdQuery:
+			cmd.query(book)
+		case cmdHalt:
+			halt, err = ob.processHalt(book, cmd.message)
+		case cmdResume:
+			halt, err = ob.processResume(book)
+		case cmdPhase:
+			auction, err = ob.processPhase(book, cmd.phase)
+		}
+		var rejection *RejectError
+		if cmd.kind == cmdNew && errors.As(err, &rejection) {
+			ob.reportRejection(order, rejection)
+		}
+		if cmd.kind != cmdQuery {
+			book.reports = nil
+			book.seq++
+			book.refreshIndicative()
+			trades := book.trades
+			if err != nil {
+				trades = nil
+			}
+			book.trades = nil
+			book.publishMarketData(trades)
+		}
+
+		res := result{auction: auction, err: err}
+		if halt != nil {
+			h := *halt
+			res.halt = &h
+		}
+		if order != nil {
+			res.order = *order
+		}
+		cmd.reply <- res
+	}
+}
+
+// submit queues a command on the sequencer of a symbol and waits for its result
+func (ob *OrderBook) submit(symbol string, cmd command) result {
+	cmd.reply = make(chan result, 1)
+	ob.bookFor(symbol).commands <- cmd
+	return <-cmd.reply
+}
+
+// MatchOrders processes a new order and attempts to match it with existing orders.
+// On return newOrder holds the state of the order after matching.
+func (ob *OrderBook) MatchOrders(newOrder *models.Order) error {
+	order := *newOrder
+	res := ob.submit(newOrder.Symbol, command{kind: cmdNew, order: &order})
+	*newOrder = res.order
+	return res.err
+}
+
+// CancelOrder cancels a resting order and removes it from the book
+func (ob *OrderBook) CancelOrder(symbol string, orderID int64) error {
+	return ob.submit(symbol, command{kind: cmdCancel, orderID: orderID}).err
+}
+
+// AmendOrder changes the price and/or quantity of a resting limit order and
+// returns its state afterwards. A nil price or zero quantity keeps the current
+// value; an amended price that crosses the book matches immediately.
+func (ob *OrderBook) AmendOrder(symbol string, orderID int64, price *models.Decimal, quantity models.Decimal) (*models.Order, error) {
+	res := ob.submit(symbol, command{kind: cmdAmend, orderID: orderID, price: price, quantity: quantity})
+	if res.err != nil {
+		return nil, res.err
+	}
+	return &res.order, nil
+}
+
+// UpdateOrderStatus changes the status and remaining quantity of a resting order.
+// Filled and canceled orders leave the book.
+func (ob *OrderBook) UpdateOrderStatus(symbol string, orderID int64, status string, remainingQuantity models.Decimal) error {
+	return ob.submit(symbol, command{kind: cmdStatus, orderID: orderID, status: status, remainingQuantity: &remainingQuantity}).err
+}
+
+// OpenOrderCount returns the number of open orders in the book of a symbol,
+// including stop orders waiting for their trigger
+func (ob *OrderBook) OpenOrderCount(symbol string) int {
+	var count int
+	ob.submit(symbol, command{kind: cmdQuery, query: func(book *symbolBook) {
+		count = len(book.index)
+	}})
+	return count
+}
+
+// HaltSymbol stops all matching in a symbol until it is resumed. GTC limit and
+// stop orders are still accepted and queue for the reopening auction.
+func (ob *OrderBook) HaltSymbol(symbol, message string) (*models.Halt, error) {
+	res := ob.submit(symbol, command{kind: cmdHalt, message: message})
+	return res.halt, res.err
+}
+
+// ResumeSymbol ends the halt of a symbol with a reopening auction and returns the
+// halt with the auction result
+func (ob *OrderBook) ResumeSymbol(symbol string) (*models.Halt, error) {
+	res := ob.submit(symbol, command{kind: cmdResume})
+	return res.halt, res.err
+}
+
+// SetPhase moves a symbol to a trading phase. Leaving the pre-open or auction
+// phase uncrosses the accumulated orders, and the auction is returned.
+func (ob *OrderBook) SetPhase(symbol, phase string) (*models.Auction, error) {
+	res := ob.submit(symbol, command{kind: cmdPhase, phase: phase})
+	return res.auction, res.err
+}
+
+// TradingState returns the phase and active halt of a symbol, with the indicative
+// auction while its orders accumulate without matching
+func (ob *OrderBook) TradingState(symbol string) models.TradingState {
+	state := models.TradingState{Symbol: symbol}
+	ob.submit(symbol, command{kind: cmdQuery, query: func(book *symbolBook) {
+		state.Phase = book.phase
+		if book.halt != nil {
+			h := *book.halt
+			state.Halt = &h
+		}
+		if book.indicative != nil {
+			a := *book.indicative
+			state.Indicative = &a
+		}
+	}})
+	return state
+}
@@ -0,0 +1,220 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"golang-order-matching-system/db"
+	"golang-order-matching-system/models"
+)
+
+// Reject codes for orders the trading phase of their symbol does not accept
+const (
+	RejectMarketClosed = "MARKET_CLOSED"
+	RejectAuctionPhase = "AUCTION_PHASE"
+)
+
+// Errors returned for phase changes and session schedules
+var (
+	ErrInvalidPhase           = errors.New("invalid trading phase")
+	ErrInvalidSessionSchedule = errors.New("invalid session schedule")
+)
+
+// validPhase reports whether phase is a known trading phase
+func validPhase(phase string) bool {
+	switch phase {
+	case models.PhasePreOpen, models.PhaseAuction, models.PhaseContinuous, models.PhaseClosed:
+		return true
+	}
+	return false
+}
+
+// callPhase reports whether orders accumulate for an auction during phase
+func callPhase(phase string) bool {
+	return phase == models.PhasePreOpen || phase == models.PhaseAuction
+}
+
+// checkPhase refuses orders the current phase of their symbol cannot take. A closed
+// symbol takes no orders; while orders accumulate for an auction or a reopening
+// only GTC limit and stop orders are queued.
+func checkPhase(book *symbolBook, order *models.Order) error {
+	switch {
+	case book.phase == models.PhaseClosed:
+		return reject(RejectMarketClosed, "%s is closed, orders are accepted again when its session opens", order.Symbol)
+	case book.matching() || isStop(order) || (order.Type == "limit" && order.TimeInForce == TimeInForceGTC):
+		return nil
+	case book.halt != nil:
+		return reject(RejectSymbolHalted, "%s is halted, only GTC limit and stop orders are accepted until it reopens", order.Symbol)
+	}
+	return reject(RejectAuctionPhase, "%s is in its %s phase, only GTC limit and stop orders are accepted until the auction uncrosses", order.Symbol, book.phase)
+}
+
+// processPhase moves a symbol to another trading phase. Leaving a call phase
+// uncrosses the orders accumulated during it at a single clearing price, unless the
+// symbol is halted, and returns that auction.
+func (ob *OrderBook) processPhase(book *symbolBook, phase string) (auction *models.Auction, err error) {
+	if !validPhase(phase) {
+		return nil, ErrInvalidPhase
+	}
+	if phase == book.phase {
+		return nil, nil
+	}
+	tx, err := db.DB.Begin()
+	if err != nil {
+		log.Printf("Failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			ob.reload(book)
+			log.Printf("Transaction rolled back for %s phase change due to error: %v", book.symbol, err)
+		}
+	}()
+
+	if err = db.SaveTradingPhaseTx(book.symbol, phase, tx); err != nil {
+		return nil, err
+	}
+	previous := book.phase
+	book.phase = phase
+
+	if callPhase(previous) && !callPhase(phase) && book.halt == nil {
+		auction = indicativeAuction(book, book.lastPrice)
+		if err = ob.uncross(book, auction, tx); err != nil {
+			return nil, err
+		}
+		if auction.Price != nil {
+			book.window.restart(auction.Price)
+		}
+	}
+	if err = ob.processTriggers(book, tx); err != nil {
+		return nil, err
+	}
+	if err = ob.commit(book, tx); err != nil {
+		log.Printf("Failed to commit %s phase change: %v", book.symbol, err)
+		return nil, err
+	}
+	log.Printf("%s moved from %s to %s", book.symbol, previous, phase)
+	return auction, nil
+}
+
+// SessionScheduler moves symbols between trading phases on a daily schedule. A
+// phase is applied when the schedule crosses into it, so a phase set by hand
+// lasts until the next scheduled change.
+type SessionScheduler struct {
+	ob        *OrderBook
+	mu        sync.Mutex
+	schedules map[string][]models.SessionPhase
+	applied   map[string]string // phase each symbol was last moved to by its schedule
+}
+
+// NewSessionScheduler creates a scheduler without schedules for the books of ob
+func NewSessionScheduler(ob *OrderBook) *SessionScheduler {
+	return &SessionScheduler{
+		ob:        ob,
+		schedules: make(map[string][]models.SessionPhase),
+		applied:   make(map[string]string),
+	}
+}
+
+// Load reads all session schedules from the database
+func (s *SessionScheduler) Load() error {
+	schedules, err := db.GetSessionSchedules()
+	if err != nil {
+		log.Printf("Failed to load session schedules: %v", err)
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedules = schedules
+	log.Printf("Session schedules loaded for %d instruments", len(schedules))
+	return nil
+}
+
+// Schedule returns the session schedule of a symbol ordered by start time
+func (s *SessionScheduler) Schedule(symbol string) []models.SessionPhase {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.SessionPhase(nil), s.schedules[symbol]...)
+}
+
+// Set validates and replaces the session schedule of a symbol, which takes effect
+// at the next tick. An empty schedule leaves the symbol in its current phase.
+func (s *SessionScheduler) Set(symbol string, schedule []models.SessionPhase) error {
+	if err := validateSchedule(schedule); err != nil {
+		return err
+	}
+	if err := db.ReplaceSessionSchedule(symbol, schedule); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(schedule) == 0 {
+		delete(s.schedules, symbol)
+	} else {
+		s.schedules[symbol] = append([]models.SessionPhase(nil), schedule...)
+	}
+	delete(s.applied, symbol)
+	return nil
+}
+
+// validateSchedule checks that a schedule has known phases and "HH:MM" start times in increasing order
+func validateSchedule(schedule []models.SessionPhase) error {
+	for i, entry := range schedule {
+		if !validPhase(entry.Phase) {
+			return fmt.Errorf("%w: unknown phase %q", ErrInvalidSessionSchedule, entry.Phase)
+		}
+		if t, err := time.Parse("15:04", entry.Start); err != nil || t.Format("15:04") != entry.Start {
+			return fmt.Errorf("%w: start %q is not an HH:MM time", ErrInvalidSessionSchedule, entry.Start)
+		}
+		if i > 0 && entry.Start <= schedule[i-1].Start {
+			return fmt.Errorf("%w: start times must increase", ErrInvalidSessionSchedule)
+		}
+	}
+	return nil
+}
+
+// scheduledPhase returns the phase a schedule puts a symbol in at the time of day
+// of now. Before the first entry the last entry of the previous day still applies.
+func scheduledPhase(schedule []models.SessionPhase, now time.Time) string {
+	clock := now.Format("15:04")
+	phase := schedule[len(schedule)-1].Phase
+	for _, entry := range schedule {
+		if entry.Start > clock {
+			break
+		}
+		phase = entry.Phase
+	}
+	return phase
+}
+
+// Run applies the schedules at every interval, reading times of day in loc. It
+// applies the current phases immediately and never returns.
+func (s *SessionScheduler) Run(loc *time.Location, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.apply(time.Now().In(loc))
+		<-ticker.C
+	}
+}
+
+// apply moves every scheduled symbol whose scheduled phase changed since the last tick
+func (s *SessionScheduler) apply(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for symbol, schedule := range s.schedules {
+		phase := scheduledPhase(schedule, now)
+		if s.applied[symbol] == phase {
+			continue
+		}
+		if _, err := s.ob.SetPhase(symbol, phase); err != nil {
+			log.Printf("Failed to move %s to its scheduled %s phase: %v", symbol, phase, err)
+			continue
+		}
+		s.applied[symbol] = phase
+	}
+}
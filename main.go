@@ -4,8 +4,10 @@ import (
     "log"
     "net/http"
     "os"
+    "time"
     "golang-order-matching-system/db"    
     "golang-order-matching-system/api" 
+    "golang-order-matching-system/engine"
     "github.com/gorilla/mux"
     "github.com/joho/godotenv"
 )
@@ -22,8 +24,26 @@ func main() {
     }
     defer db.CloseDB()
 
+    if err := api.BootstrapAdminKey(); err != nil {
+        log.Fatalf("Failed to issue admin API key: %v", err)
+    }
+
+    orderBook := engine.NewOrderBook()
+    if err := orderBook.Load(); err != nil {
+        log.Fatalf("Failed to load order book: %v", err)
+    }
+
+    // Session schedules are read in SESSION_TIMEZONE, UTC when unset
+    loc, err := time.LoadLocation(os.Getenv("SESSION_TIMEZONE"))
+    if err != nil {
+        log.Fatalf("Invalid SESSION_TIMEZONE: %v", err)
+    }
+    go orderBook.Sessions.Run(loc, time.Second)
+    go orderBook.Fees.Run(5 * time.Minute)
+
     router := mux.NewRouter()
-    api.SetupRoutes(router)
+    router.Use(api.Authenticate)
+    api.SetupRoutes(router, orderBook)
 
     port := os.Getenv("PORT")
     if port == "" {
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Account roles
+const (
+	AccountRoleTrader = "trader"
+	AccountRoleAdmin  = "admin"
+)
+
+// Account status values
+const (
+	AccountStatusActive   = "active"
+	AccountStatusDisabled = "disabled"
+)
+
+// Account is a participant that owns orders and authenticates with API keys
+type Account struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`   // "trader" or "admin"
+	Status    string    `json:"status"` // "active" or "disabled"
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsAdmin reports whether the account may use the /admin endpoints and see every order
+func (a *Account) IsAdmin() bool {
+	return a.Role == AccountRoleAdmin
+}
+
+// APIKey authenticates requests on behalf of an account
+type APIKey struct {
+	ID        int64     `json:"id"`
+	AccountID int64     `json:"account_id"`
+	Key       string    `json:"api_key"`
+	Secret    string    `json:"secret,omitempty"` // only returned when the key is created
+	Revoked   bool      `json:"revoked"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Balance is the amount of one asset owned by an account. Held funds are
+// reserved by open orders and cannot be used for new ones.
+type Balance struct {
+	AccountID int64     `json:"account_id"`
+	Asset     string    `json:"asset"`
+	Available Decimal   `json:"available"`
+	Held      Decimal   `json:"held"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
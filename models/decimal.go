@@ -0,0 +1,475 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MaxDecimalScale is the largest number of fractional digits a Decimal can carry
+const MaxDecimalScale = 18
+
+// MaxParseScale is the largest number of significant fractional digits ParseDecimal
+// accepts, the scale of the DECIMAL columns. Trailing zeros beyond it are dropped, so
+// a value written with extra zeros cannot inflate the scale of later arithmetic.
+const MaxParseScale = 8
+
+// pow10 holds the powers of ten that fit in an int64
+var pow10 = [MaxDecimalScale + 1]int64{
+	1, 10, 100, 1000, 10000, 100000, 1000000, 10000000, 100000000, 1000000000,
+	10000000000, 100000000000, 1000000000000, 10000000000000, 100000000000000,
+	1000000000000000, 10000000000000000, 100000000000000000, 1000000000000000000,
+}
+
+// ErrDecimalOverflow is returned when a value does not fit in a Decimal
+var ErrDecimalOverflow = errors.New("decimal value out of range")
+
+// RoundingMode says how the digits a result has beyond its target scale are dropped
+type RoundingMode int
+
+const (
+	RoundDown     RoundingMode = iota // toward zero
+	RoundUp                           // away from zero
+	RoundHalfEven                     // to the nearest value, ties to the even one
+)
+
+// Decimal is an exact fixed-point number stored as an integer count of units of
+// 10^-scale. The scale travels with the value so that each instrument can use its
+// own precision; values are serialized as exact strings in JSON and SQL.
+type Decimal struct {
+	units int64
+	scale int32
+}
+
+// NewDecimal returns units * 10^-scale
+func NewDecimal(units int64, scale int32) Decimal {
+	if scale < 0 || scale > MaxDecimalScale {
+		panic(fmt.Sprintf("decimal scale %d out of range", scale))
+	}
+	return Decimal{units: units, scale: scale}
+}
+
+// DecimalFromInt returns the integer n as a Decimal with scale 0
+func DecimalFromInt(n int64) Decimal {
+	return Decimal{units: n}
+}
+
+// ParseDecimal parses a plain decimal string such as "-12.3450" with at most
+// MaxParseScale significant fractional digits
+func ParseDecimal(s string) (Decimal, error) {
+	str := strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(str, "-") || strings.HasPrefix(str, "+") {
+		neg = str[0] == '-'
+		str = str[1:]
+	}
+	intPart, fracPart, _ := strings.Cut(str, ".")
+	if intPart == "" && fracPart == "" {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	if len(fracPart) > MaxParseScale {
+		if strings.TrimRight(fracPart[MaxParseScale:], "0") != "" {
+			return Decimal{}, fmt.Errorf("invalid decimal %q: more than %d fractional digits", s, MaxParseScale)
+		}
+		fracPart = fracPart[:MaxParseScale]
+	}
+	for _, c := range intPart + fracPart {
+		if c < '0' || c > '9' {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+		}
+	}
+	digits := strings.TrimLeft(intPart+fracPart, "0")
+	var units int64
+	if digits != "" {
+		var err error
+		units, err = strconv.ParseInt(digits, 10, 64)
+		if err != nil {
+			return Decimal{}, ErrDecimalOverflow
+		}
+	}
+	if neg {
+		units = -units
+	}
+	return Decimal{units: units, scale: int32(len(fracPart))}, nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics on invalid input.
+// It is intended for constants.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Units returns the unscaled integer value
+func (d Decimal) Units() int64 {
+	return d.units
+}
+
+// Scale returns the number of fractional digits
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// String formats the value with exactly Scale fractional digits
+func (d Decimal) String() string {
+	units := d.units
+	sign := ""
+	if units < 0 {
+		sign = "-"
+	}
+	digits := strconv.FormatUint(absUnits(units), 10)
+	if d.scale == 0 {
+		return sign + digits
+	}
+	if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	cut := len(digits) - int(d.scale)
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+func absUnits(units int64) uint64 {
+	if units < 0 {
+		return uint64(-(units + 1)) + 1
+	}
+	return uint64(units)
+}
+
+// big returns the unscaled value as a big.Int
+func (d Decimal) big() *big.Int {
+	return big.NewInt(d.units)
+}
+
+// fromBig converts an unscaled big.Int at the given scale back to a Decimal
+func fromBig(v *big.Int, scale int32) Decimal {
+	if !v.IsInt64() {
+		panic(ErrDecimalOverflow)
+	}
+	return Decimal{units: v.Int64(), scale: scale}
+}
+
+// Rescale returns the value with the given number of fractional digits,
+// truncating toward zero when digits are dropped
+func (d Decimal) Rescale(scale int32) Decimal {
+	if scale < 0 || scale > MaxDecimalScale {
+		panic(fmt.Sprintf("decimal scale %d out of range", scale))
+	}
+	rescaled, err := d.CheckedRescale(scale)
+	if err != nil {
+		panic(err)
+	}
+	return rescaled
+}
+
+// CheckedRescale is like Rescale but returns ErrDecimalOverflow instead of
+// panicking when the value does not fit at the larger scale
+func (d Decimal) CheckedRescale(scale int32) (Decimal, error) {
+	if scale < 0 || scale > MaxDecimalScale {
+		return Decimal{}, fmt.Errorf("decimal scale %d out of range", scale)
+	}
+	switch {
+	case scale == d.scale:
+		return d, nil
+	case scale < d.scale:
+		return Decimal{units: d.units / pow10[d.scale-scale], scale: scale}, nil
+	default:
+		v := d.big()
+		v.Mul(v, big.NewInt(pow10[scale-d.scale]))
+		if !v.IsInt64() {
+			return Decimal{}, ErrDecimalOverflow
+		}
+		return Decimal{units: v.Int64(), scale: scale}, nil
+	}
+}
+
+// align returns both values at the larger of their scales
+func align(a, b Decimal) (Decimal, Decimal) {
+	if a.scale < b.scale {
+		return a.Rescale(b.scale), b
+	}
+	if b.scale < a.scale {
+		return a, b.Rescale(a.scale)
+	}
+	return a, b
+}
+
+// Cmp returns -1, 0 or +1 depending on whether d is less than, equal to or greater
+// than e. Values of different scales are compared exactly, so it never overflows.
+func (d Decimal) Cmp(e Decimal) int {
+	if d.scale == e.scale {
+		switch {
+		case d.units < e.units:
+			return -1
+		case d.units > e.units:
+			return 1
+		}
+		return 0
+	}
+	a, b := d.big(), e.big()
+	if d.scale < e.scale {
+		a.Mul(a, big.NewInt(pow10[e.scale-d.scale]))
+	} else {
+		b.Mul(b, big.NewInt(pow10[d.scale-e.scale]))
+	}
+	return a.Cmp(b)
+}
+
+// Equal reports whether d and e have the same numeric value, whatever their scales
+func (d Decimal) Equal(e Decimal) bool {
+	return d.Cmp(e) == 0
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of d
+func (d Decimal) Sign() int {
+	switch {
+	case d.units < 0:
+		return -1
+	case d.units > 0:
+		return 1
+	}
+	return 0
+}
+
+// IsZero reports whether d is zero
+func (d Decimal) IsZero() bool {
+	return d.units == 0
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	return Decimal{units: -d.units, scale: d.scale}
+}
+
+// Abs returns |d|
+func (d Decimal) Abs() Decimal {
+	if d.units < 0 {
+		return d.Neg()
+	}
+	return d
+}
+
+// Add returns d + e at the larger of the two scales
+func (d Decimal) Add(e Decimal) Decimal {
+	a, b := align(d, e)
+	v := a.big()
+	v.Add(v, b.big())
+	return fromBig(v, a.scale)
+}
+
+// Sub returns d - e at the larger of the two scales
+func (d Decimal) Sub(e Decimal) Decimal {
+	return d.Add(e.Neg())
+}
+
+// Mul returns the exact product d * e at the sum of the two scales. It panics if
+// the product does not fit; amounts of money are rounded to their scale with MulRound.
+func (d Decimal) Mul(e Decimal) Decimal {
+	product, err := d.CheckedMul(e)
+	if err != nil {
+		panic(err)
+	}
+	return product
+}
+
+// CheckedMul is like Mul but returns an error instead of panicking when the
+// product does not fit
+func (d Decimal) CheckedMul(e Decimal) (Decimal, error) {
+	return d.CheckedMulRound(e, d.scale+e.scale, RoundDown)
+}
+
+// MulRound returns d * e rounded to the given scale with mode. The product is
+// computed exactly first, so only a result that does not fit overflows, which panics.
+func (d Decimal) MulRound(e Decimal, scale int32, mode RoundingMode) Decimal {
+	product, err := d.CheckedMulRound(e, scale, mode)
+	if err != nil {
+		panic(err)
+	}
+	return product
+}
+
+// CheckedMulRound is like MulRound but returns an error instead of panicking.
+// Use it for products of values a client controls.
+func (d Decimal) CheckedMulRound(e Decimal, scale int32, mode RoundingMode) (Decimal, error) {
+	if scale < 0 || scale > MaxDecimalScale {
+		return Decimal{}, fmt.Errorf("decimal scale %d out of range", scale)
+	}
+	v := d.big()
+	v.Mul(v, e.big())
+	// The exact product has d.scale + e.scale fractional digits
+	if shift := scale - d.scale - e.scale; shift >= 0 {
+		v.Mul(v, exp10(shift))
+	} else {
+		v = roundQuo(v, exp10(-shift), mode)
+	}
+	if !v.IsInt64() {
+		return Decimal{}, ErrDecimalOverflow
+	}
+	return Decimal{units: v.Int64(), scale: scale}, nil
+}
+
+// exp10 returns 10^n as a big.Int
+func exp10(n int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// roundQuo returns num / den rounded with mode, for a positive den
+func roundQuo(num, den *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	away := false
+	switch mode {
+	case RoundUp:
+		away = true
+	case RoundHalfEven:
+		half := new(big.Int).Abs(r)
+		half.Lsh(half, 1)
+		c := half.Cmp(den)
+		away = c > 0 || c == 0 && q.Bit(0) == 1
+	}
+	if away {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// MulQuo returns d * e / f at the given scale, truncating toward zero. The
+// intermediate product is exact, so only a result that does not fit overflows.
+// It panics if f is zero.
+func (d Decimal) MulQuo(e, f Decimal, scale int32) Decimal {
+	if f.units == 0 {
+		panic("decimal division by zero")
+	}
+	// d*e/f = d.units * e.units * 10^(scale + f.scale - d.scale - e.scale) / f.units
+	num := d.big()
+	num.Mul(num, e.big())
+	den := f.big()
+	if shift := scale + f.scale - d.scale - e.scale; shift >= 0 {
+		num.Mul(num, exp10(shift))
+	} else {
+		den.Mul(den, exp10(-shift))
+	}
+	num.Quo(num, den)
+	return fromBig(num, scale)
+}
+
+// MulInt returns d * n at the scale of d
+func (d Decimal) MulInt(n int64) Decimal {
+	v := d.big()
+	v.Mul(v, big.NewInt(n))
+	return fromBig(v, d.scale)
+}
+
+// Quo returns d / e at the given scale, truncating toward zero. It panics if e is zero.
+func (d Decimal) Quo(e Decimal, scale int32) Decimal {
+	if e.units == 0 {
+		panic("decimal division by zero")
+	}
+	// d/e = (d.units * 10^(scale + e.scale - d.scale)) / e.units at the target scale
+	num := d.big()
+	shift := scale + e.scale - d.scale
+	if shift >= 0 {
+		num.Mul(num, exp10(shift))
+	} else {
+		num.Quo(num, exp10(-shift))
+	}
+	num.Quo(num, e.big())
+	return fromBig(num, scale)
+}
+
+// Mod returns the remainder of d / e, with the sign of d
+func (d Decimal) Mod(e Decimal) Decimal {
+	a, b := align(d, e)
+	if b.units == 0 {
+		panic("decimal division by zero")
+	}
+	return Decimal{units: a.units % b.units, scale: a.scale}
+}
+
+// MinDecimal returns the smaller of a and b
+func MinDecimal(a, b Decimal) Decimal {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
+
+// MaxDecimal returns the larger of a and b
+func MaxDecimal(a, b Decimal) Decimal {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// MarshalJSON encodes the value as an exact decimal string
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// UnmarshalJSON accepts both decimal strings and JSON numbers without going through float64
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		var err error
+		if s, err = strconv.Unquote(s); err != nil {
+			return fmt.Errorf("invalid decimal %s", data)
+		}
+	} else if strings.ContainsAny(s, "eE") {
+		return fmt.Errorf("invalid decimal %s: exponent notation is not supported", data)
+	}
+	v, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Scan implements sql.Scanner for DECIMAL and integer columns
+func (d *Decimal) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Decimal{}
+		return nil
+	case []byte:
+		return d.scanString(string(v))
+	case string:
+		return d.scanString(v)
+	case int64:
+		*d = DecimalFromInt(v)
+		return nil
+	case float64:
+		return d.scanString(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Errorf("unsupported decimal source: %T", src)
+	}
+}
+
+func (d *Decimal) scanString(s string) error {
+	v, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Value implements driver.Valuer, sending the exact decimal string to the database
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
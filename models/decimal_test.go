@@ -0,0 +1,155 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"0", "0", false},
+		{"12.3450", "12.3450", false},
+		{"-0.5", "-0.5", false},
+		{"+7", "7", false},
+		{".25", "0.25", false},
+		{"3.", "3", false},
+		{"1.00000001", "1.00000001", false},
+		{"1.000000000000000000", "1.00000000", false}, // trailing zeros beyond MaxParseScale are dropped
+		{"1.000000001", "", true},                     // a significant digit beyond MaxParseScale
+		{"9223372036854775807", "9223372036854775807", false},
+		{"9223372036854775808", "", true},
+		{"92233720368.54775808", "", true},
+		{"", "", true},
+		{".", "", true},
+		{"1.2.3", "", true},
+		{"1e5", "", true},
+		{"abc", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDecimal(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseDecimal(%q) = %s, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDecimal(%q): %v", tt.in, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseDecimal(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMulIsExact(t *testing.T) {
+	tests := []struct{ a, b, want string }{
+		{"0.5", "0.5", "0.25"},
+		{"100.25", "0.333", "33.38325"},
+		{"110.00", "1.00000000", "110.0000000000"},
+		{"-1.5", "2", "-3.0"},
+		{"3", "4", "12"},
+	}
+	for _, tt := range tests {
+		got := MustParseDecimal(tt.a).Mul(MustParseDecimal(tt.b))
+		if got.String() != tt.want {
+			t.Errorf("%s × %s = %s, want %s", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMulRound(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		scale int32
+		mode  RoundingMode
+		want  string
+	}{
+		{"100.25", "0.333", 2, RoundDown, "33.38"},
+		{"100.25", "0.333", 2, RoundUp, "33.39"},
+		{"100.25", "0.333", 2, RoundHalfEven, "33.38"},
+		{"0.5", "0.5", 1, RoundHalfEven, "0.2"}, // tie to even
+		{"0.5", "0.7", 1, RoundHalfEven, "0.4"}, // tie to even
+		{"0.5", "0.5", 1, RoundUp, "0.3"},
+		{"-0.5", "0.5", 1, RoundDown, "-0.2"},
+		{"-0.5", "0.5", 1, RoundUp, "-0.3"},
+		{"-0.5", "0.7", 1, RoundHalfEven, "-0.4"},
+		{"0.01", "0.01", 1, RoundUp, "0.1"},
+		{"0.01", "0.01", 1, RoundHalfEven, "0.0"},
+		{"2", "3", 4, RoundDown, "6.0000"},
+		// The exact product has 16 decimal places and does not fit in int64 at that scale
+		{"100000.00000000", "1000.00000000", 8, RoundUp, "100000000.00000000"},
+	}
+	for _, tt := range tests {
+		got := MustParseDecimal(tt.a).MulRound(MustParseDecimal(tt.b), tt.scale, tt.mode)
+		if got.String() != tt.want {
+			t.Errorf("%s × %s at scale %d, mode %d = %s, want %s", tt.a, tt.b, tt.scale, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestMulOverflow(t *testing.T) {
+	big := MustParseDecimal("100000.00000000")
+	if _, err := big.CheckedMul(MustParseDecimal("1000.00000000")); !errors.Is(err, ErrDecimalOverflow) {
+		t.Errorf("CheckedMul: got %v, want ErrDecimalOverflow", err)
+	}
+	if _, err := MustParseDecimal("92233720368").CheckedMulRound(DecimalFromInt(100), 8, RoundDown); !errors.Is(err, ErrDecimalOverflow) {
+		t.Errorf("CheckedMulRound: got %v, want ErrDecimalOverflow", err)
+	}
+	if _, err := MustParseDecimal("0.1").CheckedMulRound(DecimalFromInt(1), MaxDecimalScale+1, RoundDown); err == nil {
+		t.Error("CheckedMulRound accepted a scale beyond MaxDecimalScale")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Mul did not panic on overflow")
+		}
+	}()
+	big.Mul(big)
+}
+
+func TestQuo(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		scale int32
+		want  string
+	}{
+		{"1", "3", 4, "0.3333"},
+		{"-1", "3", 4, "-0.3333"},
+		{"10.00", "4", 2, "2.50"},
+		{"2.5", "0.5", 0, "5"},
+		{"1", "0.00000003", 2, "33333333.33"},
+	}
+	for _, tt := range tests {
+		got := MustParseDecimal(tt.a).Quo(MustParseDecimal(tt.b), tt.scale)
+		if got.String() != tt.want {
+			t.Errorf("%s / %s at scale %d = %s, want %s", tt.a, tt.b, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestMulQuo(t *testing.T) {
+	// 7 × 3 / 9 with the intermediate product kept exact
+	got := MustParseDecimal("7.00000000").MulQuo(DecimalFromInt(3), DecimalFromInt(9), 8)
+	if got.String() != "2.33333333" {
+		t.Errorf("MulQuo = %s, want 2.33333333", got)
+	}
+}
+
+func TestCmpAcrossScales(t *testing.T) {
+	if MustParseDecimal("1.50").Cmp(MustParseDecimal("1.5")) != 0 {
+		t.Error("1.50 and 1.5 compare unequal")
+	}
+	// Aligning these to one scale would overflow
+	huge := DecimalFromInt(9223372036854775807)
+	if huge.Cmp(MustParseDecimal("0.00000001")) <= 0 {
+		t.Error("max int64 compares below 0.00000001")
+	}
+	if _, err := huge.CheckedRescale(8); !errors.Is(err, ErrDecimalOverflow) {
+		t.Errorf("CheckedRescale: got %v, want ErrDecimalOverflow", err)
+	}
+}
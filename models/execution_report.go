@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Execution report types: what happened to an order
+const (
+	ExecAccepted        = "accepted"         // the order was stored and its funds held
+	ExecPartiallyFilled = "partially_filled" // a fill left part of the order open
+	ExecFilled          = "filled"           // a fill completed the order
+	ExecCanceled        = "canceled"         // the order or its unfilled remainder was canceled
+	ExecRejected        = "rejected"         // the engine refused the order; it was never stored
+	ExecAmended         = "amended"          // the price or quantity was amended
+	ExecTriggered       = "triggered"        // a stop order was triggered and converted
+	ExecRestated        = "restated"         // the remaining quantity or status was changed without a fill
+)
+
+// ExecutionReport is a change of an order, sent to its account. Seq increases
+// with every report of an account in commit order, so a client that reconnects
+// can ask for the reports after the last Seq it saw.
+type ExecutionReport struct {
+	Seq               int64     `json:"seq"`
+	AccountID         int64     `json:"account_id"`
+	OrderID           int64     `json:"order_id"` // zero for rejected orders
+	Symbol            string    `json:"symbol"`
+	Side              string    `json:"side"`
+	OrderType         string    `json:"order_type"`
+	ExecType          string    `json:"exec_type"`
+	Status            string    `json:"status"` // order status after the change, "rejected" for rejections
+	Price             *Decimal  `json:"price,omitempty"`
+	Quantity          Decimal   `json:"quantity"`
+	FilledQuantity    Decimal   `json:"filled_quantity"` // cumulative
+	RemainingQuantity Decimal   `json:"remaining_quantity"`
+	LastPrice         *Decimal  `json:"last_price,omitempty"` // fills only
+	LastQuantity      *Decimal  `json:"last_quantity,omitempty"`
+	TradeID           int       `json:"trade_id,omitempty"`
+	RejectCode        string    `json:"reject_code,omitempty"` // rejections only
+	Reason            string    `json:"reason,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
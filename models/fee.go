@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// FeeTier is one step of the fee schedule of an instrument. An account pays the
+// rates of the highest tier whose minimum volume its trailing 30-day volume
+// reaches. Rates are fractions of the trade notional; a negative maker rate is a
+// rebate.
+type FeeTier struct {
+	MinVolume Decimal `json:"min_volume"` // trailing 30-day volume in the quote asset
+	MakerRate Decimal `json:"maker_rate"`
+	TakerRate Decimal `json:"taker_rate"`
+}
+
+// TradedVolume is the quote notional an account traded on an instrument
+type TradedVolume struct {
+	AccountID int64   `json:"account_id"`
+	Symbol    string  `json:"symbol"`
+	Volume    Decimal `json:"volume"`
+}
+
+// AccountFees shows the fee tier an account currently trades at on an instrument
+type AccountFees struct {
+	AccountID  int64     `json:"account_id"`
+	Symbol     string    `json:"symbol"`
+	Volume30d  Decimal   `json:"volume_30d"`
+	Tier       FeeTier   `json:"tier"`
+	TierIndex  int       `json:"tier_index"`
+	Schedule   []FeeTier `json:"schedule"`
+	ComputedAt time.Time `json:"computed_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Halt reasons
+const (
+	HaltReasonCircuitBreaker = "circuit_breaker"
+	HaltReasonManual         = "manual"
+)
+
+// Halt records a trading halt of a symbol. While a symbol is halted nothing
+// matches; it resumes through a reopening auction.
+type Halt struct {
+	ID             int64      `json:"id"`
+	Symbol         string     `json:"symbol"`
+	Reason         string     `json:"reason"` // "circuit_breaker" or "manual"
+	Message        string     `json:"message,omitempty"`
+	ReferencePrice *Decimal   `json:"reference_price,omitempty"` // price the circuit breaker measured the move from
+	TriggerPrice   *Decimal   `json:"trigger_price,omitempty"`   // trade price that tripped the circuit breaker
+	HaltedAt       time.Time  `json:"halted_at"`
+	ResumedAt      *time.Time `json:"resumed_at,omitempty"`
+	Reopening      *Auction   `json:"reopening,omitempty"` // result of the reopening auction
+}
+
+// Auction is the outcome of uncrossing a book at a single clearing price
+type Auction struct {
+	Symbol    string    `json:"symbol"`
+	Price     *Decimal  `json:"price,omitempty"` // nil when no orders cross
+	Volume    Decimal   `json:"volume"`
+	Imbalance Decimal   `json:"imbalance"` // unmatched quantity on the larger side at the price
+	Trades    int       `json:"trades"`
+	At        time.Time `json:"at"`
+}
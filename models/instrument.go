@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Instrument status values
+const (
+	InstrumentStatusTrading = "trading"
+	InstrumentStatusHalted  = "halted"
+	InstrumentStatusClosed  = "closed"
+)
+
+// Matching algorithms an instrument can allocate price levels with
+const (
+	MatchingFIFO    = "fifo"     // price-time priority
+	MatchingProRata = "pro_rata" // proportional to resting size, residual in time priority
+	MatchingTopFIFO = "fifo_top" // the top order first, then price-time priority
+	MatchingLMM     = "lmm"      // the lead market maker's share first, then pro-rata
+)
+
+// Instrument holds the trading rules for a symbol
+type Instrument struct {
+	Symbol               string    `json:"symbol"`
+	BaseAsset            string    `json:"base_asset"`
+	QuoteAsset           string    `json:"quote_asset"`
+	TickSize             Decimal   `json:"tick_size"`
+	LotSize              Decimal   `json:"lot_size"`
+	MinQuantity          Decimal   `json:"min_quantity"`
+	MaxQuantity          Decimal   `json:"max_quantity"` // zero means no limit
+	MinNotional          Decimal   `json:"min_notional"`
+	MaxNotional          Decimal   `json:"max_notional"`           // zero means no limit
+	PricePrecision       int32     `json:"price_precision"`        // number of decimal places in prices
+	MarketCollarPct      Decimal   `json:"market_collar_pct"`      // percentage around the best opposite price market orders may trade at
+	CircuitBreakerPct    Decimal   `json:"circuit_breaker_pct"`    // price move within the window that halts trading, zero disables
+	CircuitBreakerWindow int       `json:"circuit_breaker_window"` // rolling window of the circuit breaker in seconds
+	MatchingAlgorithm    string    `json:"matching_algorithm"`     // how a price level is allocated, "fifo" by default
+	TopOrderMax          Decimal   `json:"top_order_max"`          // most the top order gets ahead of the level under fifo_top, zero means no limit
+	LMMAccountID         int64     `json:"lmm_account_id"`         // lead market maker under lmm
+	LMMPct               Decimal   `json:"lmm_pct"`                // share of each level allocation reserved for the lead market maker
+	Status               string    `json:"status"`                 // "trading", "halted" or "closed"
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Journal types
+const (
+	JournalTypeHold       = "hold"       // funds moved from available to held for an order
+	JournalTypeRelease    = "release"    // held funds returned to available
+	JournalTypeTrade      = "trade"      // settlement of a trade between buyer and seller, including fees
+	JournalTypeDeposit    = "deposit"    // funds entering from outside the exchange
+	JournalTypeWithdrawal = "withdrawal" // funds leaving the exchange
+)
+
+// Balance buckets a ledger entry applies to
+const (
+	BucketAvailable = "available"
+	BucketHeld      = "held"
+)
+
+// System accounts only exist in the ledger and have no balance
+const (
+	ExternalAccountID int64 = 0  // counterparty of deposits and withdrawals, the world outside the exchange
+	FeeAccountID      int64 = -1 // collects trading fees and pays maker rebates
+)
+
+// Journal is a balanced set of ledger entries posted together: for every asset
+// the amounts of its entries sum to zero
+type Journal struct {
+	ID          int64         `json:"id"`
+	Type        string        `json:"type"`
+	ReferenceID int64         `json:"reference_id"` // order ID for holds and releases, trade ID for trades
+	Entries     []LedgerEntry `json:"entries"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// LedgerEntry is one signed movement of an asset in a balance bucket of an
+// account: positive amounts credit the bucket, negative amounts debit it
+type LedgerEntry struct {
+	ID          int64     `json:"id"`
+	JournalID   int64     `json:"journal_id"`
+	Type        string    `json:"type"`         // type of the journal
+	ReferenceID int64     `json:"reference_id"` // reference of the journal
+	AccountID   int64     `json:"account_id"`
+	Asset       string    `json:"asset"`
+	Bucket      string    `json:"bucket"` // "available" or "held"
+	Amount      Decimal   `json:"amount"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// LedgerImbalance reports an asset, journal or balance whose ledger entries do not add up
+type LedgerImbalance struct {
+	Asset     string  `json:"asset"`
+	JournalID int64   `json:"journal_id,omitempty"`
+	AccountID int64   `json:"account_id,omitempty"`
+	Bucket    string  `json:"bucket,omitempty"`
+	Expected  Decimal `json:"expected"`
+	Actual    Decimal `json:"actual"`
+}
+
+// LedgerCheck is the result of a ledger consistency check
+type LedgerCheck struct {
+	Balanced   bool              `json:"balanced"`
+	Journals   int64             `json:"journals"`
+	Entries    int64             `json:"entries"`
+	Imbalances []LedgerImbalance `json:"imbalances"`
+	CheckedAt  time.Time         `json:"checked_at"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// Market data channels a client can subscribe to per symbol
+const (
+	ChannelDepth  = "depth"  // aggregated price levels, a snapshot then incremental updates
+	ChannelTrades = "trades" // public trades
+	ChannelBBO    = "bbo"    // best bid and offer
+)
+
+// Market data event types
+const (
+	EventDepthSnapshot = "depth_snapshot"
+	EventDepthUpdate   = "depth_update"
+	EventTrade         = "trade"
+	EventBBO           = "bbo"
+)
+
+// PriceLevel is the visible quantity resting at one price and the number of orders behind it
+type PriceLevel struct {
+	Price    Decimal `json:"price"`
+	Quantity Decimal `json:"quantity"` // zero in a depth update means the level is gone
+	Orders   int     `json:"orders"`
+}
+
+// DepthSnapshot is the aggregated depth of a symbol at engine sequence number Seq
+type DepthSnapshot struct {
+	Symbol string       `json:"symbol"`
+	Seq    int64        `json:"seq"`
+	Group  *Decimal     `json:"group,omitempty"` // price bucket size, omitted for single prices
+	Bids   []PriceLevel `json:"bids"`            // best first
+	Asks   []PriceLevel `json:"asks"`
+}
+
+// BookOrder is a resting order in an order-by-order snapshot, without its owner
+type BookOrder struct {
+	OrderID  int64     `json:"order_id"`
+	Position int       `json:"position"` // queue position within its level, starting at 1
+	Quantity Decimal   `json:"quantity"` // visible quantity; the hidden reserve of an iceberg is left out
+	QueuedAt time.Time `json:"queued_at"`
+	Top      bool      `json:"top,omitempty"` // set the best price on its side; fifo_top instruments fill it first
+}
+
+// BookLevel is one price level of an order-by-order snapshot with its orders in priority order
+type BookLevel struct {
+	Price    *Decimal    `json:"price"` // null for resting market orders, which trade before any price level
+	Quantity Decimal     `json:"quantity"`
+	Orders   []BookOrder `json:"orders"`
+}
+
+// BookSnapshot is every resting order of a symbol at engine sequence number Seq
+type BookSnapshot struct {
+	Symbol string      `json:"symbol"`
+	Seq    int64       `json:"seq"`
+	Bids   []BookLevel `json:"bids"` // best first
+	Asks   []BookLevel `json:"asks"`
+}
+
+// MarketEvent is a market data message of one symbol. Seq is the engine sequence
+// number of the symbol after the command that produced the event; depth updates
+// also carry the Seq of the previous depth update so clients can detect gaps.
+type MarketEvent struct {
+	Type    string       `json:"type"`
+	Symbol  string       `json:"symbol"`
+	Seq     int64        `json:"seq"`
+	PrevSeq int64        `json:"prev_seq,omitempty"` // depth updates only
+	Bids    []PriceLevel `json:"bids,omitempty"`     // best first; an omitted side has no levels or no changes
+	Asks    []PriceLevel `json:"asks,omitempty"`
+	Bid     *PriceLevel  `json:"bid,omitempty"` // bbo only, omitted when the side is empty
+	Ask     *PriceLevel  `json:"ask,omitempty"`
+	Trade   *Trade       `json:"trade,omitempty"` // public trade without accounts or fees
+}
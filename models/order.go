@@ -4,13 +4,24 @@ import "time"
 
 type Order struct {
     ID               int64     `json:"id"`
+    AccountID        int64     `json:"account_id"` // owner of the order
     Symbol           string    `json:"symbol"`
     Side             string    `json:"side"` // "buy" or "sell"
-    Type             string    `json:"type"` // "limit" or "market"
-    Price            *float64  `json:"price,omitempty"` // pointer to allow NULL for market orders
-    Quantity         int       `json:"quantity"`
-    RemainingQuantity int      `json:"remaining_quantity"`
+    Type             string    `json:"type"` // "limit", "market", "stop" or "stop_limit"
+    TimeInForce      string    `json:"time_in_force"` // "GTC", "IOC" or "FOK"
+    Price            *Decimal  `json:"price,omitempty"` // pointer to allow NULL for market orders
+    StopPrice        *Decimal  `json:"stop_price,omitempty"` // trigger price for stop and stop_limit orders
+    ProtectionPrice  *Decimal  `json:"protection_price,omitempty"` // worst price a market order may trade at, set by the engine
+    Quantity         Decimal   `json:"quantity"`
+    RemainingQuantity Decimal  `json:"remaining_quantity"`
+    DisplayQuantity  *Decimal  `json:"display_quantity,omitempty"` // iceberg slice size, nil for fully visible orders
+    VisibleQuantity  Decimal   `json:"visible_quantity,omitzero"` // currently displayed slice of an iceberg
+    PostOnly         bool      `json:"post_only,omitempty"` // never take liquidity
+    PostOnlyMode     string    `json:"post_only_mode,omitempty"` // "reject" (default) or "reprice" when a post-only order would cross
+    Hold             Decimal   `json:"hold"` // funds still held for the order: quote asset for buys, base asset for sells
+    STPMode          string    `json:"stp_mode,omitempty"` // self-trade prevention: "cancel_newest", "cancel_oldest", "cancel_both" or "decrement_and_cancel"
     Status           string    `json:"status"` // "open", "partially_filled", "filled", "canceled"
     CreatedAt        time.Time `json:"created_at"`
     UpdatedAt        time.Time `json:"updated_at"`
+    QueuedAt         time.Time `json:"queued_at"` // time priority within the price level
 }
\ No newline at end of file
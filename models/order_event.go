@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// OrderEvent records something that happened to an order outside of a plain fill,
+// such as a stop being triggered
+type OrderEvent struct {
+	ID        int64     `json:"id"`
+	OrderID   int64     `json:"order_id"`
+	Symbol    string    `json:"symbol"`
+	Type      string    `json:"type"`
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RiskLimits are pre-trade limits for an account, an instrument, or an account on
+// one instrument. AccountID 0 applies to every account and an empty Symbol to every
+// instrument; an order must pass the tightest of all limits that apply to it.
+// Zero values mean no limit.
+type RiskLimits struct {
+	AccountID        int64     `json:"account_id"`
+	Symbol           string    `json:"symbol"`
+	MaxOrderQuantity Decimal   `json:"max_order_quantity"`
+	MaxNotional      Decimal   `json:"max_notional"`    // price × quantity in the quote asset
+	MaxOpenOrders    int       `json:"max_open_orders"` // per symbol, including pending stops
+	PriceBandPct     Decimal   `json:"price_band_pct"`  // maximum distance of a limit price from the last trade price or mid
+	UpdatedAt        time.Time `json:"updated_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// Trading phases of a symbol
+const (
+	PhasePreOpen    = "pre_open"   // orders accumulate for the opening auction
+	PhaseAuction    = "auction"    // call auction, orders accumulate until it uncrosses
+	PhaseContinuous = "continuous" // orders match on arrival
+	PhaseClosed     = "closed"     // no new orders are accepted
+)
+
+// SessionPhase is an entry of a trading session schedule: the phase a symbol
+// enters at Start each day and keeps until the next entry starts
+type SessionPhase struct {
+	Start string `json:"start"` // "HH:MM" in the session time zone
+	Phase string `json:"phase"`
+}
+
+// TradingState is the current phase of a symbol. While orders accumulate without
+// matching it carries the indicative result of uncrossing the book now.
+type TradingState struct {
+	Symbol     string   `json:"symbol"`
+	Phase      string   `json:"phase"`
+	Halt       *Halt    `json:"halt,omitempty"`
+	Indicative *Auction `json:"indicative,omitempty"`
+}
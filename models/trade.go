@@ -4,11 +4,18 @@ import "time"
 
 // Trade represents a completed trade between a buy and sell order
 type Trade struct {
-	ID          int       `json:"id"`
-	Symbol      string    `json:"symbol"`
-	BuyOrderID  int64     `json:"buy_order_id"`
-	SellOrderID int64     `json:"sell_order_id"`
-	Price       float64   `json:"price"`
-	Quantity    int       `json:"quantity"`
-	CreatedAt   time.Time `json:"created_at"`
-}
\ No newline at end of file
+	ID            int       `json:"id"`
+	Symbol        string    `json:"symbol"`
+	BuyOrderID    int64     `json:"buy_order_id"`
+	SellOrderID   int64     `json:"sell_order_id"`
+	BuyAccountID  int64     `json:"buy_account_id,omitempty"`
+	SellAccountID int64     `json:"sell_account_id,omitempty"`
+	Price         Decimal   `json:"price"`
+	Quantity      Decimal   `json:"quantity"`
+	TakerSide     string    `json:"taker_side"` // side of the incoming order
+	BuyFee        Decimal   `json:"buy_fee,omitzero"`
+	BuyFeeAsset   string    `json:"buy_fee_asset,omitempty"` // buyers pay fees in the base asset they receive
+	SellFee       Decimal   `json:"sell_fee,omitzero"`
+	SellFeeAsset  string    `json:"sell_fee_asset,omitempty"` // sellers pay fees in the quote asset they receive
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -1,21 +1,150 @@
 -- Drop tables if they exist (order matters because of FK constraints)
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS journals;
+DROP TABLE IF EXISTS execution_reports;
+DROP TABLE IF EXISTS order_events;
 DROP TABLE IF EXISTS trades;
 DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS session_schedules;
+DROP TABLE IF EXISTS trading_phases;
+DROP TABLE IF EXISTS halts;
+DROP TABLE IF EXISTS risk_limits;
+DROP TABLE IF EXISTS fee_tiers;
+DROP TABLE IF EXISTS instruments;
+DROP TABLE IF EXISTS balances;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS accounts;
 
 CREATE DATABASE IF NOT EXISTS order_matching;
 USE order_matching;
 
+CREATE TABLE IF NOT EXISTS instruments (
+    symbol VARCHAR(10) PRIMARY KEY,
+    base_asset VARCHAR(10) NOT NULL,
+    quote_asset VARCHAR(10) NOT NULL,
+    tick_size DECIMAL(20,8) NOT NULL,
+    lot_size DECIMAL(20,8) NOT NULL,
+    min_quantity DECIMAL(20,8) NOT NULL DEFAULT 0,
+    max_quantity DECIMAL(20,8) NOT NULL DEFAULT 0,
+    min_notional DECIMAL(20,8) NOT NULL DEFAULT 0,
+    max_notional DECIMAL(20,8) NOT NULL DEFAULT 0,
+    price_precision INT NOT NULL,
+    market_collar_pct DECIMAL(10,4) NOT NULL DEFAULT 10,
+    circuit_breaker_pct DECIMAL(10,4) NOT NULL DEFAULT 0,
+    circuit_breaker_window INT NOT NULL DEFAULT 300,
+    matching_algorithm VARCHAR(20) NOT NULL DEFAULT 'fifo',
+    top_order_max DECIMAL(20,8) NOT NULL DEFAULT 0,
+    lmm_account_id BIGINT NOT NULL DEFAULT 0,
+    lmm_pct DECIMAL(10,4) NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'trading',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+-- Maker and taker rates per instrument, tiered by trailing 30-day quote volume
+CREATE TABLE IF NOT EXISTS fee_tiers (
+    symbol VARCHAR(10) NOT NULL,
+    min_volume DECIMAL(30,8) NOT NULL,
+    maker_rate DECIMAL(10,6) NOT NULL,
+    taker_rate DECIMAL(10,6) NOT NULL,
+    PRIMARY KEY (symbol, min_volume),
+    FOREIGN KEY (symbol) REFERENCES instruments(symbol) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS accounts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    role VARCHAR(20) NOT NULL DEFAULT 'trader',
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    account_id BIGINT NOT NULL,
+    api_key VARCHAR(64) NOT NULL UNIQUE,
+    secret VARCHAR(64) NOT NULL,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+);
+
+-- Trading halts of a symbol and the reopening auctions that ended them
+CREATE TABLE IF NOT EXISTS halts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    symbol VARCHAR(10) NOT NULL,
+    reason VARCHAR(20) NOT NULL,
+    message VARCHAR(255) NOT NULL DEFAULT '',
+    reference_price DECIMAL(12,8),
+    trigger_price DECIMAL(12,8),
+    halted_at DATETIME NOT NULL,
+    resumed_at DATETIME,
+    reopen_price DECIMAL(12,8),
+    reopen_volume DECIMAL(20,8) NOT NULL DEFAULT 0,
+    FOREIGN KEY (symbol) REFERENCES instruments(symbol) ON DELETE CASCADE
+);
+
+-- Current trading phase of each symbol; symbols without a row trade continuously
+CREATE TABLE IF NOT EXISTS trading_phases (
+    symbol VARCHAR(10) PRIMARY KEY,
+    phase VARCHAR(20) NOT NULL,
+    updated_at DATETIME NOT NULL,
+    FOREIGN KEY (symbol) REFERENCES instruments(symbol) ON DELETE CASCADE
+);
+
+-- Daily session schedule: the phase each symbol enters at start_time (HH:MM in SESSION_TIMEZONE)
+CREATE TABLE IF NOT EXISTS session_schedules (
+    symbol VARCHAR(10) NOT NULL,
+    start_time CHAR(5) NOT NULL,
+    phase VARCHAR(20) NOT NULL,
+    PRIMARY KEY (symbol, start_time),
+    FOREIGN KEY (symbol) REFERENCES instruments(symbol) ON DELETE CASCADE
+);
+
+-- Pre-trade risk limits; account_id 0 and an empty symbol apply to all accounts and instruments
+CREATE TABLE IF NOT EXISTS risk_limits (
+    account_id BIGINT NOT NULL DEFAULT 0,
+    symbol VARCHAR(10) NOT NULL DEFAULT '',
+    max_order_quantity DECIMAL(20,8) NOT NULL DEFAULT 0,
+    max_notional DECIMAL(30,8) NOT NULL DEFAULT 0,
+    max_open_orders INT NOT NULL DEFAULT 0,
+    price_band_pct DECIMAL(10,4) NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (account_id, symbol)
+);
+
+CREATE TABLE IF NOT EXISTS balances (
+    account_id BIGINT NOT NULL,
+    asset VARCHAR(10) NOT NULL,
+    available DECIMAL(30,8) NOT NULL DEFAULT 0,
+    held DECIMAL(30,8) NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (account_id, asset),
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+);
+
 CREATE TABLE IF NOT EXISTS orders (
     id INT AUTO_INCREMENT PRIMARY KEY,
+    account_id BIGINT NOT NULL,
     symbol VARCHAR(10) NOT NULL,
     side VARCHAR(10) NOT NULL,
     type VARCHAR(10) NOT NULL,
+    time_in_force VARCHAR(3) NOT NULL DEFAULT 'GTC',
     price DECIMAL(12,8),
-    quantity INT NOT NULL,
-    remaining_quantity INT NOT NULL,
+    stop_price DECIMAL(12,8),
+    protection_price DECIMAL(12,8),
+    quantity DECIMAL(20,8) NOT NULL,
+    remaining_quantity DECIMAL(20,8) NOT NULL,
+    display_quantity DECIMAL(20,8),
+    visible_quantity DECIMAL(20,8) NOT NULL DEFAULT 0,
+    post_only BOOLEAN NOT NULL DEFAULT FALSE,
+    hold DECIMAL(30,8) NOT NULL DEFAULT 0,
+    stp_mode VARCHAR(30) NOT NULL DEFAULT '',
     status VARCHAR(20) NOT NULL,
     created_at DATETIME NOT NULL,
-    updated_at DATETIME NOT NULL
+    updated_at DATETIME NOT NULL,
+    queued_at DATETIME(6) NOT NULL,
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
 );
 
 CREATE TABLE IF NOT EXISTS trades (
@@ -23,13 +152,80 @@ CREATE TABLE IF NOT EXISTS trades (
     symbol VARCHAR(10) NOT NULL,
     buy_order_id INT NOT NULL,
     sell_order_id INT NOT NULL,
+    buy_account_id BIGINT NOT NULL,
+    sell_account_id BIGINT NOT NULL,
     price DECIMAL(12,8) NOT NULL,
-    quantity INT NOT NULL,
+    quantity DECIMAL(20,8) NOT NULL,
+    taker_side VARCHAR(10) NOT NULL DEFAULT '',
+    buy_fee DECIMAL(30,8) NOT NULL DEFAULT 0,
+    buy_fee_asset VARCHAR(10) NOT NULL DEFAULT '',
+    sell_fee DECIMAL(30,8) NOT NULL DEFAULT 0,
+    sell_fee_asset VARCHAR(10) NOT NULL DEFAULT '',
     created_at DATETIME NOT NULL,
     FOREIGN KEY (buy_order_id) REFERENCES orders(id),
     FOREIGN KEY (sell_order_id) REFERENCES orders(id)
 );
 
+CREATE TABLE IF NOT EXISTS order_events (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    symbol VARCHAR(10) NOT NULL,
+    type VARCHAR(30) NOT NULL,
+    details VARCHAR(255) NOT NULL,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+-- Order updates streamed to each account; id is the sequence number clients resume from
+CREATE TABLE IF NOT EXISTS execution_reports (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    account_id BIGINT NOT NULL,
+    order_id INT NOT NULL DEFAULT 0,
+    symbol VARCHAR(10) NOT NULL,
+    side VARCHAR(10) NOT NULL,
+    order_type VARCHAR(10) NOT NULL,
+    exec_type VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    price DECIMAL(12,8),
+    quantity DECIMAL(20,8) NOT NULL,
+    filled_quantity DECIMAL(20,8) NOT NULL,
+    remaining_quantity DECIMAL(20,8) NOT NULL,
+    last_price DECIMAL(12,8),
+    last_quantity DECIMAL(20,8),
+    trade_id INT NOT NULL DEFAULT 0,
+    reject_code VARCHAR(30) NOT NULL DEFAULT '',
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS journals (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    type VARCHAR(20) NOT NULL,
+    reference_id BIGINT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+-- Signed double-entry movements; account 0 is the outside world for deposits and withdrawals
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    journal_id BIGINT NOT NULL,
+    account_id BIGINT NOT NULL,
+    asset VARCHAR(10) NOT NULL,
+    bucket VARCHAR(10) NOT NULL,
+    amount DECIMAL(30,8) NOT NULL,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (journal_id) REFERENCES journals(id)
+);
+
+-- Default instrument used by the examples in README and test cases
+INSERT INTO instruments (symbol, base_asset, quote_asset, tick_size, lot_size, min_quantity, max_quantity,
+    min_notional, max_notional, price_precision, market_collar_pct, status, created_at, updated_at)
+VALUES ('AAPL', 'AAPL', 'USD', 0.01, 1, 1, 0, 0, 0, 2, 10, 'trading', NOW(), NOW());
+
+-- Bootstrap admin account for creating accounts and API keys; the server issues
+-- its key on first start (see README)
+INSERT INTO accounts (id, name, role, status, created_at) VALUES (1, 'admin', 'admin', 'active', NOW());
+
 CREATE USER IF NOT EXISTS 'kushagra'@'localhost' IDENTIFIED BY 'yourpassword';
 GRANT ALL PRIVILEGES ON order_matching.* TO 'kushagra'@'localhost';
 FLUSH PRIVILEGES;
@@ -37,4 +233,11 @@ FLUSH PRIVILEGES;
 -- Indexes
 CREATE INDEX idx_orders_symbol_side_price_time ON orders(symbol, side, price, created_at);
 CREATE INDEX idx_orders_status ON orders(status);
-CREATE INDEX idx_trades_symbol ON trades(symbol,created_at);
\ No newline at end of file
+CREATE INDEX idx_trades_symbol ON trades(symbol,created_at);
+CREATE INDEX idx_order_events_order ON order_events(order_id);
+CREATE INDEX idx_execution_reports_account ON execution_reports(account_id, id);
+CREATE INDEX idx_orders_account ON orders(account_id, status);
+CREATE INDEX idx_trades_buy_account ON trades(buy_account_id, symbol, created_at);
+CREATE INDEX idx_trades_sell_account ON trades(sell_account_id, symbol, created_at);
+CREATE INDEX idx_ledger_entries_account ON ledger_entries(account_id, asset, id);
+CREATE INDEX idx_journals_reference ON journals(type, reference_id);
\ No newline at end of file
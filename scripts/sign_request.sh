@@ -0,0 +1,32 @@
+#!/bin/bash
+
+# Sends a signed request to the API.
+# Usage: API_KEY=... API_SECRET=... ./scripts/sign_request.sh METHOD PATH [BODY]
+# Example: ./scripts/sign_request.sh POST /orders '{"symbol":"AAPL","side":"buy","type":"limit","price":99.00,"quantity":5}'
+
+set -e
+
+METHOD="$1"
+URI="$2"
+BODY="${3:-}"
+BASE_URL="${BASE_URL:-http://localhost:${PORT:-8080}}"
+
+if [ -z "$METHOD" ] || [ -z "$URI" ] || [ -z "$API_KEY" ] || [ -z "$API_SECRET" ]; then
+    echo "Usage: API_KEY=... API_SECRET=... $0 METHOD PATH [BODY]"
+    exit 1
+fi
+
+TIMESTAMP=$(($(date +%s) * 1000))
+NONCE=$(openssl rand -hex 16)
+SIGNATURE=$(printf '%s\n%s\n%s\n%s\n%s' "$METHOD" "$URI" "$TIMESTAMP" "$NONCE" "$BODY" \
+    | openssl dgst -sha256 -hmac "$API_SECRET" -hex | sed 's/^.* //')
+
+curl -s -X "$METHOD" \
+    -H "Content-Type: application/json" \
+    -H "X-API-Key: $API_KEY" \
+    -H "X-Timestamp: $TIMESTAMP" \
+    -H "X-Nonce: $NONCE" \
+    -H "X-Signature: $SIGNATURE" \
+    ${BODY:+-d "$BODY"} \
+    "$BASE_URL$URI"
+echo
@@ -20,3 +20,11 @@ func JSONErrorResponse(w http.ResponseWriter, status int, message string) {
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
+
+
+// JSONErrorCodeResponse writes an error with a machine-readable code alongside the message
+func JSONErrorCodeResponse(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message, "code": code})
+}